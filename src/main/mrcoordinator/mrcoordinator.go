@@ -0,0 +1,95 @@
+package main
+
+// mrcoordinator starts a MapReduce coordinator process that hands out
+// map and reduce tasks to mrworker processes over RPC.
+//
+// Usage: mrcoordinator [-codec json|gob|binary] [-split-size bytes] [-compression none|gzip] [-secondary-sort] inputfiles...
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"mapreduce"
+)
+
+const nReduce = 10
+
+func main() {
+	codec := flag.String("codec", "json", "intermediate file codec: json, gob, or binary")
+	splitSize := flag.Int64("split-size", 0, "maximum bytes per map task's input split; 0 gives one split per file")
+	compression := flag.String("compression", "none", "intermediate file compression: none or gzip")
+	secondarySort := flag.Bool("secondary-sort", false, "treat every key the app's Map emits as a mapreduce.CompositeKey(primary, secondary): partition on primary only and deliver Reduce each primary's values sorted by secondary")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: mrcoordinator [-codec json|gob|binary] [-split-size bytes] [-compression none|gzip] [-secondary-sort] inputfiles...\n")
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	codecName, err := parseCodecName(*codec)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	compressionName, err := parseCompressionName(*compression)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	c := mapreduce.NewCoordinator(context.Background(), "job", flag.Args(), nReduce, mapreduce.JobOptions{Codec: codecName, SplitSize: *splitSize, Compression: compressionName, SecondarySort: *secondarySort})
+	for !c.Done() {
+		time.Sleep(time.Second)
+	}
+	printCounters(c.Counters())
+}
+
+func printCounters(counters map[string]int64) {
+	if len(counters) == 0 {
+		return
+	}
+	names := make([]string, 0, len(counters))
+	for name := range counters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Println("job counters:")
+	for _, name := range names {
+		fmt.Printf("  %s: %d\n", name, counters[name])
+	}
+}
+
+func parseCodecName(s string) (mapreduce.CodecName, error) {
+	switch mapreduce.CodecName(s) {
+	case mapreduce.JSONCodecName, mapreduce.GobCodecName, mapreduce.BinaryCodecName:
+		return mapreduce.CodecName(s), nil
+	default:
+		return "", fmt.Errorf("unknown -codec %q: want json, gob, or binary", s)
+	}
+}
+
+// parseCompressionName only accepts the compression names this build
+// can actually write; mapreduce.SnappyCompression and
+// mapreduce.ZstdCompression exist as named constants for callers that
+// embed this package as a library and want the exhaustiveness check
+// against compressWriter's switch, but mrcoordinator has no reason to
+// accept flag values it knows will fail every map task.
+func parseCompressionName(s string) (mapreduce.CompressionName, error) {
+	switch mapreduce.CompressionName(s) {
+	case "none":
+		return mapreduce.NoCompression, nil
+	case mapreduce.GzipCompression:
+		return mapreduce.GzipCompression, nil
+	default:
+		return "", fmt.Errorf("unknown -compression %q: want none or gzip", s)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+// mrworker starts a worker process that connects to a running
+// mrcoordinator and executes the map and reduce functions exported by
+// the given application plugin. The plugin may also export Partition
+// and Combine to opt into mapreduce.doMap's partitioner/combiner
+// hooks; both are optional and default to nil (today's ihash-based
+// partitioning, no combining) when absent.
+//
+// Usage: mrworker app.so
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"plugin"
+
+	"mapreduce"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintf(os.Stderr, "Usage: mrworker app.so\n")
+		os.Exit(1)
+	}
+
+	mapF, reduceF, partitionF, combineF := loadPlugin(os.Args[1])
+	mapreduce.RunWorker(context.Background(), mapF, reduceF, partitionF, combineF)
+}
+
+func loadPlugin(filename string) (
+	func(string, string) []mapreduce.KeyValue,
+	func(string, []string) string,
+	func(string, int) int,
+	func(string, []string) string,
+) {
+	p, err := plugin.Open(filename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cannot load plugin %s: %v\n", filename, err)
+		os.Exit(1)
+	}
+
+	xmapF, err := p.Lookup("Map")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cannot find Map in %s: %v\n", filename, err)
+		os.Exit(1)
+	}
+	xreduceF, err := p.Lookup("Reduce")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cannot find Reduce in %s: %v\n", filename, err)
+		os.Exit(1)
+	}
+
+	var partitionF func(string, int) int
+	if xpartitionF, err := p.Lookup("Partition"); err == nil {
+		partitionF = xpartitionF.(func(string, int) int)
+	}
+
+	var combineF func(string, []string) string
+	if xcombineF, err := p.Lookup("Combine"); err == nil {
+		combineF = xcombineF.(func(string, []string) string)
+	}
+
+	return xmapF.(func(string, string) []mapreduce.KeyValue),
+		xreduceF.(func(string, []string) string),
+		partitionF,
+		combineF
+}
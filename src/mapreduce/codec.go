@@ -0,0 +1,135 @@
+package mapreduce
+
+import (
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"io"
+)
+
+// KVEncoder writes successive KeyValue pairs to an intermediate file.
+type KVEncoder interface {
+	Encode(kv *KeyValue) error
+}
+
+// KVDecoder reads back the KeyValue pairs written by a KVEncoder.
+type KVDecoder interface {
+	Decode(kv *KeyValue) error
+}
+
+// Codec controls how doMap and doReduce serialize KeyValue pairs to
+// and from intermediate files. JSONCodec matches the format doMap has
+// always used; GobCodec and BinaryCodec trade that format's
+// readability for less overhead when keys and values are arbitrary
+// bytes rather than valid UTF-8 strings.
+type Codec interface {
+	NewEncoder(w io.Writer) KVEncoder
+	NewDecoder(r io.Reader) KVDecoder
+}
+
+// JSONCodec is the default Codec: one JSON object per KeyValue.
+type JSONCodec struct{}
+
+func (JSONCodec) NewEncoder(w io.Writer) KVEncoder { return &jsonEncoder{json.NewEncoder(w)} }
+func (JSONCodec) NewDecoder(r io.Reader) KVDecoder { return &jsonDecoder{json.NewDecoder(r)} }
+
+type jsonEncoder struct{ enc *json.Encoder }
+
+func (e *jsonEncoder) Encode(kv *KeyValue) error { return e.enc.Encode(kv) }
+
+type jsonDecoder struct{ dec *json.Decoder }
+
+func (d *jsonDecoder) Decode(kv *KeyValue) error { return d.dec.Decode(kv) }
+
+// GobCodec serializes KeyValue pairs with encoding/gob.
+type GobCodec struct{}
+
+func (GobCodec) NewEncoder(w io.Writer) KVEncoder { return &gobEncoder{gob.NewEncoder(w)} }
+func (GobCodec) NewDecoder(r io.Reader) KVDecoder { return &gobDecoder{gob.NewDecoder(r)} }
+
+type gobEncoder struct{ enc *gob.Encoder }
+
+func (e *gobEncoder) Encode(kv *KeyValue) error { return e.enc.Encode(kv) }
+
+type gobDecoder struct{ dec *gob.Decoder }
+
+func (d *gobDecoder) Decode(kv *KeyValue) error { return d.dec.Decode(kv) }
+
+// BinaryCodec is a compact length-prefixed format: each KeyValue is
+// written as a uint32 length followed by the raw key bytes, then a
+// uint32 length followed by the raw value bytes. It avoids JSON's
+// quoting/escaping overhead and places no constraints on what bytes
+// keys and values may contain.
+type BinaryCodec struct{}
+
+func (BinaryCodec) NewEncoder(w io.Writer) KVEncoder { return &binaryEncoder{w} }
+func (BinaryCodec) NewDecoder(r io.Reader) KVDecoder { return &binaryDecoder{r} }
+
+type binaryEncoder struct{ w io.Writer }
+
+func (e *binaryEncoder) Encode(kv *KeyValue) error {
+	if err := writeLengthPrefixed(e.w, []byte(kv.Key)); err != nil {
+		return err
+	}
+	return writeLengthPrefixed(e.w, []byte(kv.Value))
+}
+
+type binaryDecoder struct{ r io.Reader }
+
+func (d *binaryDecoder) Decode(kv *KeyValue) error {
+	key, err := readLengthPrefixed(d.r)
+	if err != nil {
+		return err
+	}
+	value, err := readLengthPrefixed(d.r)
+	if err != nil {
+		return err
+	}
+	kv.Key = string(key)
+	kv.Value = string(value)
+	return nil
+}
+
+func writeLengthPrefixed(w io.Writer, b []byte) error {
+	if err := binary.Write(w, binary.LittleEndian, uint32(len(b))); err != nil {
+		return err
+	}
+	_, err := w.Write(b)
+	return err
+}
+
+func readLengthPrefixed(r io.Reader) ([]byte, error) {
+	var n uint32
+	if err := binary.Read(r, binary.LittleEndian, &n); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// CodecName identifies a Codec across an RPC boundary, where the
+// Codec interface value itself cannot be transmitted. The zero value
+// selects JSONCodec.
+type CodecName string
+
+const (
+	JSONCodecName   CodecName = "json"
+	GobCodecName    CodecName = "gob"
+	BinaryCodecName CodecName = "binary"
+)
+
+// codecByName resolves a CodecName to the Codec it identifies,
+// defaulting to JSONCodec for the zero value or any unrecognized name.
+func codecByName(name CodecName) Codec {
+	switch name {
+	case GobCodecName:
+		return GobCodec{}
+	case BinaryCodecName:
+		return BinaryCodec{}
+	default:
+		return JSONCodec{}
+	}
+}
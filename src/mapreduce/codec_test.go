@@ -0,0 +1,69 @@
+package mapreduce
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestCodecRoundTrip checks that every Codec implementation can
+// decode exactly the KeyValue pairs it encoded, in order, including
+// values that would trip up a naive delimiter-based format (embedded
+// newlines and quotes).
+func TestCodecRoundTrip(t *testing.T) {
+	kvs := []KeyValue{
+		{Key: "word", Value: "1"},
+		{Key: "with\nnewline", Value: "with\"quote"},
+		{Key: "", Value: ""},
+	}
+
+	codecs := map[string]Codec{
+		"json":   JSONCodec{},
+		"gob":    GobCodec{},
+		"binary": BinaryCodec{},
+	}
+
+	for name, codec := range codecs {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			enc := codec.NewEncoder(&buf)
+			for _, kv := range kvs {
+				kv := kv
+				if err := enc.Encode(&kv); err != nil {
+					t.Fatalf("encode %v: %v", kv, err)
+				}
+			}
+
+			dec := codec.NewDecoder(&buf)
+			for i, want := range kvs {
+				var got KeyValue
+				if err := dec.Decode(&got); err != nil {
+					t.Fatalf("decode kv %d: %v", i, err)
+				}
+				if got != want {
+					t.Fatalf("kv %d: got %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+// TestCodecByName checks that each CodecName resolves to the Codec
+// it names, and that an unrecognized or zero-value name falls back to
+// JSONCodec.
+func TestCodecByName(t *testing.T) {
+	cases := []struct {
+		name CodecName
+		want Codec
+	}{
+		{JSONCodecName, JSONCodec{}},
+		{GobCodecName, GobCodec{}},
+		{BinaryCodecName, BinaryCodec{}},
+		{"", JSONCodec{}},
+		{CodecName("bogus"), JSONCodec{}},
+	}
+	for _, c := range cases {
+		if got := codecByName(c.name); got != c.want {
+			t.Fatalf("codecByName(%q) = %T, want %T", c.name, got, c.want)
+		}
+	}
+}
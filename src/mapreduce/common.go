@@ -0,0 +1,63 @@
+package mapreduce
+
+import "fmt"
+
+// KeyValue is a type used to hold the key/value pairs passed from a
+// map function to the reduce functions.
+type KeyValue struct {
+	Key   string
+	Value string
+}
+
+// reduceName constructs the name of the intermediate file that map
+// task mapTask writes for reduce task reduceTask.
+func reduceName(jobName string, mapTask int, reduceTask int) string {
+	return fmt.Sprintf("mr-%s-%d-%d", jobName, mapTask, reduceTask)
+}
+
+// mergeName constructs the name of the final output file written by
+// reduce task reduceTask.
+func mergeName(jobName string, reduceTask int) string {
+	return fmt.Sprintf("mr-%s-res-%d", jobName, reduceTask)
+}
+
+// MapOptions bundles doMap's optional knobs so that Sequential and
+// doMap itself take one argument for them instead of growing a new
+// positional parameter every time a request adds a hook. The zero
+// value reproduces doMap's original behavior: JSONCodec, ihash-based
+// partitioning, no combining, and the whole-file (non-streaming)
+// read path.
+type MapOptions struct {
+	Codec             Codec                                    // defaults to JSONCodec when nil
+	PartitionF        func(key string, nReduce int) int        // defaults to ihash(key) % nReduce when nil
+	CombineF          func(key string, values []string) string // skipped when nil
+	Streaming         bool                                     // use doMap's bounded-memory path
+	ChunkSize         int                                      // chunk size for the streaming path; 0 selects DefaultChunkSize
+	InputFormat       InputFormat                              // how doMapStreaming splits input into records; defaults to LineInputFormat when nil
+	SplitSize         int64                                    // maximum bytes per map task's input split; 0 gives one split per file, matching doMap's original behavior
+	SpillSize         int                                      // maximum buffered bytes across partitions before doMap's buffered path spills to intermediate files; 0 disables spilling and buffers every partition in full
+	WriteBufferSize   int                                      // size of the buffered writer wrapping each intermediate file; 0 selects DefaultWriteBufferSize
+	SkipBadRecords    bool                                     // recover a panicking mapF call in the streaming path and skip just that record instead of failing the whole task
+	MaxSkipRatio      float64                                  // fraction of a split's records SkipBadRecords may skip before doMapStreaming gives up and returns an error; <= 0 means unlimited
+	Compression       CompressionName                          // how to compress intermediate files; "" (NoCompression) writes them uncompressed
+	SaltKey           func(key string) bool                    // opt-in: reports whether a map-emitted key is hot enough to spread across SaltBuckets sub-partitions instead of funneling every one of its values through the single reduce task PartitionF would otherwise send them all to; nil disables salting. Framework-managed re-aggregation of a hot key's spread-out partial results (see finalizeSalting) is wired up for Sequential only; RunWorker never sets SaltKey, so Coordinator/RunWorker jobs are not salted at all
+	SaltBuckets       int                                      // number of sub-partitions SaltKey spreads a hot key across; ignored when SaltKey is nil, and <= 1 also disables salting
+	EncodeParallelism int                                      // max concurrent goroutines encoding doMap's buffered-path partition files; 0 or 1 encodes them one at a time like doMap's original behavior
+	MMapInput         bool                                     // memory-map split.File instead of reading it with ioutil.ReadAll; only takes effect on doMap's whole-file path (Split{File} with Start == End == 0); on platforms mmapFile does not support, doMap returns mmapFile's error instead
+	SecondarySort     bool                                     // treat every key mapF emits as a CompositeKey(primary, secondary): doMap partitions on primary only, and doReduce delivers each primary's values sorted by secondary instead of in arrival order
+}
+
+// withDefaults returns a copy of opts with nil Codec/PartitionF/
+// InputFormat replaced by doMap's defaults.
+func (opts MapOptions) withDefaults() MapOptions {
+	if opts.Codec == nil {
+		opts.Codec = JSONCodec{}
+	}
+	if opts.PartitionF == nil {
+		opts.PartitionF = func(key string, nReduce int) int { return ihash(key) % nReduce }
+	}
+	if opts.InputFormat == nil {
+		opts.InputFormat = LineInputFormat{}
+	}
+	return opts
+}
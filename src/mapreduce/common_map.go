@@ -1,84 +1,170 @@
 package mapreduce
 
 import (
-	"encoding/json"
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
 	"hash/fnv"
+	"io"
 	"io/ioutil"
 	"log"
 	"os"
+	"strings"
+	"sync"
 )
 
+// DefaultChunkSize is the chunk size the streaming map path reads
+// when the caller does not specify one.
+const DefaultChunkSize = 16 * 1024 * 1024 // 16MB
+
+// maxLineSize bounds how long a single line the streaming path scans
+// may be. It is deliberately independent of ChunkSize: ChunkSize only
+// controls how often mapF is invoked, and a small ChunkSize should not
+// make doMapStreaming fail on inputs with a few long lines.
+const maxLineSize = 64 * 1024 * 1024 // 64MB
+
+// DefaultWriteBufferSize is the buffer size every intermediate file
+// writer uses when the caller does not specify one.
+const DefaultWriteBufferSize = 64 * 1024 // 64KB
+
+// writeBufferSize returns opts.WriteBufferSize, defaulting to
+// DefaultWriteBufferSize when it is unset.
+func writeBufferSize(opts MapOptions) int {
+	if opts.WriteBufferSize <= 0 {
+		return DefaultWriteBufferSize
+	}
+	return opts.WriteBufferSize
+}
+
+// readWhole reads and transparently decompresses all of inputFile,
+// doMap's original whole-file input path.
+func readWhole(inputFile *os.File, fileName string) ([]byte, error) {
+	r, err := decompress(bufio.NewReader(inputFile))
+	if err != nil {
+		return nil, fmt.Errorf("decompress file:%s cause error:%w", fileName, err)
+	}
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read file:%s cause error:%w", fileName, err)
+	}
+	return content, nil
+}
+
+// readMMapped is doMap's MMapInput alternative to readWhole: it maps
+// inputFile into memory via mmapFile instead of copying it into a
+// fresh Go byte slice with ioutil.ReadAll, so the OS page cache is
+// used directly and mapF's later string(inputContent) conversion is
+// the only copy this path ever makes. The caller must call the
+// returned unmap once it is done with the returned slice (typically
+// deferred), since the slice is only valid while the mapping is live.
+//
+// A compressed input still needs decompressing into a fresh buffer
+// regardless, since a compressed stream's decoded size isn't known up
+// front; only the uncompressed case gets the full benefit of skipping
+// ReadAll. For a compressed input readMMapped therefore unmaps as soon
+// as it has decompressed the data, and returns a no-op unmap instead.
+func readMMapped(inputFile *os.File, fileName string) (content []byte, unmap func() error, err error) {
+	data, unmapData, err := mmapFile(inputFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("mmap file:%s cause error:%w", fileName, err)
+	}
+
+	if !bytes.HasPrefix(data, gzipMagic) && !bytes.HasPrefix(data, bzip2Magic) && !bytes.HasPrefix(data, zstdMagic) {
+		return data, unmapData, nil
+	}
+
+	defer unmapData()
+	r, err := decompress(bufio.NewReader(bytes.NewReader(data)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("decompress file:%s cause error:%w", fileName, err)
+	}
+	content, err = ioutil.ReadAll(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read file:%s cause error:%w", fileName, err)
+	}
+	return content, func() error { return nil }, nil
+}
+
+// doMap manages one map task: it reads split's byte range of
+// split.File, calls the user-defined map function (mapF) for that
+// range's contents, and partitions mapF's output into nReduce
+// intermediate files. It returns an error instead of panicking on any
+// IO failure, so callers embedding this package as a library can
+// retry, skip, or abort the task themselves. It also checks ctx before
+// doing any work and again before each chunk of the streaming path,
+// returning ctx.Err() as soon as ctx is done instead of finishing a
+// task nobody wants anymore.
+//
+// There is one intermediate file per reduce task. The file name
+// includes both the map task number and the reduce task number; use
+// the filename generated by reduceName(jobName, mapTask, r) as the
+// intermediate file for reduce task r. opts.PartitionF (ihash(key) %
+// nReduce by default) picks r for each key/value pair.
 func doMap(
+	ctx context.Context,
 	jobName string, // the name of the MapReduce job
 	mapTask int, // which map task this is
-	inFile string,
+	split Split, // the input split (byte range) this task reads; a Split with only File set covers the whole file
 	nReduce int, // the number of reduce task that will be run ("R" in the paper)
 	mapF func(filename string, contents string) []KeyValue,
-) {
-	//
-	// doMap manages one map task: it should read one of the input files
-	// (inFile), call the user-defined map function (mapF) for that file's
-	// contents, and partition mapF's output into nReduce intermediate files.
-	//
-	// There is one intermediate file per reduce task. The file name
-	// includes both the map task number and the reduce task number. Use
-	// the filename generated by reduceName(jobName, mapTask, r)
-	// as the intermediate file for reduce task r. Call ihash() (see
-	// below) on each key, mod nReduce, to pick r for a key/value pair.
-	//
-	// mapF() is the map function provided by the application. The first
-	// argument should be the input file name, though the map function
-	// typically ignores it. The second argument should be the entire
-	// input file contents. mapF() returns a slice containing the
-	// key/value pairs for reduce; see common.go for the definition of
-	// KeyValue.
-	//
-	// Look at Go's ioutil and os packages for functions to read
-	// and write files.
-	//
-	// Coming up with a scheme for how to format the key/value pairs on
-	// disk can be tricky, especially when taking into account that both
-	// keys and values could contain newlines, quotes, and any other
-	// character you can think of.
-	//
-	// One format often used for serializing data to a byte stream that the
-	// other end can correctly reconstruct is JSON. You are not required to
-	// use JSON, but as the output of the reduce tasks *must* be JSON,
-	// familiarizing yourself with it here may prove useful. You can write
-	// out a data structure as a JSON string to a file using the commented
-	// code below. The corresponding decoding functions can be found in
-	// common_reduce.go.
-	//
-	//   enc := json.NewEncoder(file)
-	//   for _, kv := ... {
-	//     err := enc.Encode(&kv)
-	//
-	// Remember to close the file after you have written all the values!
-	//
-	// Your code here (Part I).
-	//
-
-	inputFile, err := os.Open(inFile)
-	if err != nil {
-		log.Panicf("open file:%s cause error:%s\n", inFile, err.Error())
-		return
+	opts MapOptions, // codec, partitioner/combiner hooks, and the streaming toggle; zero value reproduces the original behavior
+) error {
+	if err := ctx.Err(); err != nil {
+		return err
 	}
-	defer func() {
-		if err := inputFile.Close(); err != nil {
-			log.Panicf("close file:%s cause error:%s\n", inFile, err.Error())
+
+	opts = opts.withDefaults()
+	if opts.SecondarySort {
+		opts.PartitionF = secondarySortPartitionF(opts.PartitionF)
+	}
+
+	if opts.Streaming {
+		return doMapStreaming(ctx, jobName, mapTask, split, nReduce, mapF, opts)
+	}
+
+	// This whole-file path keeps kvList and an index per partition in
+	// memory for the entire input; doMapStreaming above is the
+	// bounded-memory alternative for inputs too large to hold at once.
+
+	var inputContent []byte
+	if split.Start == 0 && split.End == 0 {
+		inputFile, err := os.Open(split.File)
+		if err != nil {
+			return fmt.Errorf("open file:%s cause error:%w", split.File, err)
 		}
-	}()
+		defer inputFile.Close()
 
-	inputContent, err := ioutil.ReadAll(inputFile)
-	if err != nil  {
-		log.Panicf("read file:%s cause error:%s\n", inFile, err.Error())
-		return
+		if opts.MMapInput {
+			var unmap func() error
+			inputContent, unmap, err = readMMapped(inputFile, split.File)
+			if err != nil {
+				return err
+			}
+			defer unmap()
+		} else {
+			inputContent, err = readWhole(inputFile, split.File)
+			if err != nil {
+				return err
+			}
+		}
+	} else {
+		content, err := readSplitContent(split, opts.InputFormat)
+		if err != nil {
+			return err
+		}
+		inputContent = []byte(content)
+	}
+
+	kvList := saltKeyValues(mapF(split.File, string(inputContent)), opts.SaltKey, opts.SaltBuckets)
+
+	if opts.SpillSize > 0 {
+		return spillMap(ctx, jobName, mapTask, kvList, nReduce, opts)
 	}
 
 	fileIndies := make(map[string][]int)
-	kvList := mapF(inFile, string(inputContent))
 	for index, kvPair := range kvList {
-		r := ihash(kvPair.Key) % nReduce
+		r := opts.PartitionF(kvPair.Key, nReduce)
 		reduceFileName := reduceName(jobName, mapTask, r)
 
 		if indexList, ok := fileIndies[reduceFileName]; ok {
@@ -88,30 +174,466 @@ func doMap(
 		}
 	}
 
+	return writePartitions(ctx, fileIndies, kvList, opts)
+}
+
+// writePartitions encodes every partition in fileIndies to its
+// intermediate file, up to opts.EncodeParallelism of them at once
+// instead of one at a time, so CPU-bound codecs (JSON in particular)
+// don't serialize a map task that produced many partitions. It returns
+// the first error any partition's write reported; the others are still
+// allowed to finish rather than being aborted mid-write.
+func writePartitions(ctx context.Context, fileIndies map[string][]int, kvList []KeyValue, opts MapOptions) error {
+	type partition struct {
+		fileName string
+		indies   []int
+	}
+	partitions := make([]partition, 0, len(fileIndies))
 	for fileName, indies := range fileIndies {
-		func() {
-			outFile, err := os.OpenFile(fileName, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+		partitions = append(partitions, partition{fileName, indies})
+	}
+
+	parallelism := opts.EncodeParallelism
+	if parallelism <= 0 {
+		parallelism = 1
+	}
+
+	sem := make(chan struct{}, parallelism)
+	errs := make([]error, len(partitions))
+	var wg sync.WaitGroup
+	for i, p := range partitions {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, p partition) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			errs[i] = writePartitionFile(p.fileName, opts.Codec, writeBufferSize(opts), opts.Compression, combine(kvList, p.indies, opts.CombineF))
+		}(i, p)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// spillMap is doMap's memory-bounded alternative to buffering the
+// whole partition index (fileIndies) and every partition's full KV
+// slice before writing anything: it walks kvList in slices of roughly
+// opts.SpillSize bytes and streams each slice straight to its
+// partition's intermediate file via the same partitionWriter/
+// writeChunk machinery doMapStreaming uses for its chunks, so at most
+// one slice's worth of per-partition buffering is ever live at once.
+// mapF has already returned the full kvList by the time spillMap
+// runs, so this bounds the partitioning/writing overhead, not mapF's
+// own memory use; opts.Streaming (which also bounds how much of the
+// input mapF ever sees at once) is the option to reach for when mapF
+// itself needs to run in bounded memory.
+//
+// Just like doMapStreaming's chunking, combineF only ever sees the
+// values within one spill, not every value for a key across the whole
+// task's output.
+func spillMap(ctx context.Context, jobName string, mapTask int, kvList []KeyValue, nReduce int, opts MapOptions) (err error) {
+	writers := make([]*partitionWriter, nReduce)
+	for r := 0; r < nReduce; r++ {
+		w, werr := newPartitionWriter(reduceName(jobName, mapTask, r), opts.Codec, writeBufferSize(opts), opts.Compression)
+		if werr != nil {
+			err = werr
+			break
+		}
+		writers[r] = w
+	}
+	defer func() {
+		for _, w := range writers {
+			if w == nil {
+				continue
+			}
+			if err != nil {
+				w.discard()
+				continue
+			}
+			if cerr := w.closeAndRename(); cerr != nil {
+				err = cerr
+			}
+		}
+	}()
+	if err != nil {
+		return err
+	}
+
+	start := 0
+	size := 0
+	for i, kv := range kvList {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		size += len(kv.Key) + len(kv.Value)
+		if size >= opts.SpillSize {
+			if err := writeChunk(kvList[start:i+1], writers, opts.PartitionF, opts.CombineF, nReduce); err != nil {
+				return err
+			}
+			start = i + 1
+			size = 0
+		}
+	}
+	if start < len(kvList) {
+		if err := writeChunk(kvList[start:], writers, opts.PartitionF, opts.CombineF, nReduce); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writePartitionFile writes kvs to fileName via a temp-file-then-
+// rename so that a crashed or re-executed map task can never leave a
+// partially-written or duplicated intermediate file behind: the
+// rename is atomic, so reduce workers either see the old file or the
+// new one, never a mix of both. Encoding goes through a bufio.Writer
+// of bufSize bytes instead of hitting tmpFile directly on every
+// Encode call, since encoders otherwise issue one write(2) per
+// KeyValue, and through compression's compressor when compression is
+// not NoCompression.
+func writePartitionFile(fileName string, codec Codec, bufSize int, compression CompressionName, kvs []KeyValue) error {
+	tmpFile, err := ioutil.TempFile("./", "mr-tmp-*")
+	if err != nil {
+		return fmt.Errorf("create temp file cause error:%w", err)
+	}
+
+	encErr := func() error {
+		defer tmpFile.Close()
+		bw := bufio.NewWriterSize(tmpFile, bufSize)
+		cw, err := compressWriter(bw, compression)
+		if err != nil {
+			return err
+		}
+		enc := codec.NewEncoder(cw)
+		for _, kvPair := range kvs {
+			if err := enc.Encode(&kvPair); err != nil {
+				return fmt.Errorf("encode error:%w", err)
+			}
+		}
+		if err := cw.Close(); err != nil {
+			return fmt.Errorf("close compressed writer for file:%s cause error:%w", tmpFile.Name(), err)
+		}
+		if err := bw.Flush(); err != nil {
+			return fmt.Errorf("flush file:%s cause error:%w", tmpFile.Name(), err)
+		}
+		return nil
+	}()
+	if encErr != nil {
+		os.Remove(tmpFile.Name())
+		return encErr
+	}
+
+	if err := os.Rename(tmpFile.Name(), fileName); err != nil {
+		return fmt.Errorf("rename file:%s to:%s cause error:%w", tmpFile.Name(), fileName, err)
+	}
+	return nil
+}
+
+// doMapStreaming is the bounded-memory alternative to doMap's
+// whole-file path: it reads split a record at a time via
+// opts.InputFormat (line-oriented text by default), accumulates
+// records into a chunk of roughly chunkSize bytes, calls mapF once per
+// chunk, and streams the resulting KVs straight to the intermediate
+// file for their partition instead of buffering the whole input and
+// its index in memory first. It keeps exactly nReduce files open at a
+// time.
+//
+// Because mapF only ever sees one chunk at once, combineF can only
+// combine values that land in the same chunk, not every value for a
+// key across the whole input; callers that need whole-key combining
+// and cannot afford the memory of the buffered path should combine
+// again in doReduce instead.
+//
+// Inputs whose records are not delimited the way opts.InputFormat
+// expects (mapF expecting a structurally complete document) should
+// not use this path — use the buffered doMap path instead.
+func doMapStreaming(
+	ctx context.Context,
+	jobName string,
+	mapTask int,
+	split Split,
+	nReduce int,
+	mapF func(filename string, contents string) []KeyValue,
+	opts MapOptions, // already defaulted by the caller (doMap)
+) (err error) {
+	chunkSize := opts.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+
+	reader, inputFile, err := newSplitRecordReader(split, opts.InputFormat)
+	if err != nil {
+		return err
+	}
+	defer inputFile.Close()
+
+	writers := make([]*partitionWriter, nReduce)
+	for r := 0; r < nReduce; r++ {
+		w, werr := newPartitionWriter(reduceName(jobName, mapTask, r), opts.Codec, writeBufferSize(opts), opts.Compression)
+		if werr != nil {
+			err = werr
+			break
+		}
+		writers[r] = w
+	}
+	// Only rename partition temp files into place once every chunk has
+	// been written successfully; on any error along the way, discard
+	// them instead so a reduce task can never see a partial map output
+	// left behind by a failed or interrupted map task.
+	defer func() {
+		for _, w := range writers {
+			if w == nil {
+				continue
+			}
 			if err != nil {
-				log.Panicf("open file:%s cause error:%s\n", fileName, err.Error())
-				return
+				w.discard()
+				continue
+			}
+			if cerr := w.closeAndRename(); cerr != nil {
+				err = cerr
 			}
-			func() {
-				defer func() {
-					if err := outFile.Close(); err != nil {
-						log.Panicf("close file:%s cause error:%s\n",fileName, err.Error())
-					}
-				}()
-
-				enc := json.NewEncoder(outFile)
-				for _, index := range indies {
-					kvPair := kvList[index]
-					if err := enc.Encode(KeyValue{kvPair.Key, kvPair.Value}); err != nil {
-						log.Panicf("encode error:%s\n", err.Error())
-					}
-				}
-			}()
-		}()
+		}
+	}()
+	if err != nil {
+		return err
+	}
+
+	if opts.SkipBadRecords {
+		return doMapStreamingSkipBadRecords(ctx, reader, split, nReduce, mapF, opts, writers)
+	}
+
+	flush := func(chunk *strings.Builder) error {
+		if chunk.Len() == 0 {
+			return nil
+		}
+		kvs := saltKeyValues(mapF(split.File, chunk.String()), opts.SaltKey, opts.SaltBuckets)
+		err := writeChunk(kvs, writers, opts.PartitionF, opts.CombineF, nReduce)
+		chunk.Reset()
+		return err
+	}
+
+	var chunk strings.Builder
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		record, rerr := reader.Read()
+		if rerr != nil && rerr != io.EOF {
+			return fmt.Errorf("scan file:%s cause error:%w", split.File, rerr)
+		}
+		if rerr == io.EOF {
+			break
+		}
+		if chunk.Len() > 0 && chunk.Len()+len(record)+1 > chunkSize {
+			if err := flush(&chunk); err != nil {
+				return err
+			}
+		}
+		chunk.WriteString(record)
+		chunk.WriteByte('\n')
+	}
+	return flush(&chunk)
+}
+
+// doMapStreamingSkipBadRecords is doMapStreaming's path for
+// opts.SkipBadRecords: instead of batching many records into one
+// mapF call per chunk, it calls mapF once per record, so a single
+// panicking record can be recovered, logged, and skipped without
+// dragging down every other record that would otherwise have shared
+// its chunk. It gives up and returns an error once the fraction of
+// skipped records exceeds opts.MaxSkipRatio, so a systematically
+// broken input still fails the task instead of silently discarding
+// most of it.
+func doMapStreamingSkipBadRecords(
+	ctx context.Context,
+	reader *splitRecordReader,
+	split Split,
+	nReduce int,
+	mapF func(filename string, contents string) []KeyValue,
+	opts MapOptions,
+	writers []*partitionWriter,
+) error {
+	maxSkipRatio := opts.MaxSkipRatio
+	if maxSkipRatio <= 0 {
+		maxSkipRatio = 1
+	}
+
+	var offset, seen, skipped int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		record, rerr := reader.Read()
+		if rerr != nil && rerr != io.EOF {
+			return fmt.Errorf("scan file:%s cause error:%w", split.File, rerr)
+		}
+		if rerr == io.EOF {
+			break
+		}
+		seen++
+
+		kvs, ok := callMapF(mapF, split.File, record, offset, opts)
+		offset += int64(len(record)) + 1
+		if !ok {
+			skipped++
+			if float64(skipped)/float64(seen) > maxSkipRatio {
+				return fmt.Errorf("mapreduce: skipped %d/%d records in %s, exceeding max skip ratio %.4f", skipped, seen, split.File, maxSkipRatio)
+			}
+			continue
+		}
+
+		kvs = saltKeyValues(kvs, opts.SaltKey, opts.SaltBuckets)
+		if err := writeChunk(kvs, writers, opts.PartitionF, opts.CombineF, nReduce); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// callMapF invokes mapF on one record. When opts.SkipBadRecords is
+// set, it recovers a panic instead of letting it kill the whole task:
+// it logs the record's byte offset within the split, increments the
+// "skipped_records" counter (see Counters), and returns ok == false so
+// the caller drops that record's output and moves on. Without
+// SkipBadRecords the panic is left to propagate, matching every other
+// mapF call in this package.
+func callMapF(mapF func(filename string, contents string) []KeyValue, filename, record string, offset int64, opts MapOptions) (kvs []KeyValue, ok bool) {
+	if !opts.SkipBadRecords {
+		return mapF(filename, record), true
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("mapreduce: skipping bad record at offset %d in %s: %v", offset, filename, r)
+			Count("skipped_records", 1)
+			kvs, ok = nil, false
+		}
+	}()
+	return mapF(filename, record), true
+}
+
+// partitionWriter buffers one partition's share of a map task's
+// output in a temp file so it can be renamed into place atomically
+// once every chunk has been written, exactly like the buffered doMap
+// path. Encoding goes through compressed, a compressor (a no-op one
+// for NoCompression) wrapping bw, a bufio.Writer, instead of hitting
+// tmpFile directly on every encode call.
+type partitionWriter struct {
+	finalName  string
+	tmpFile    *os.File
+	bw         *bufio.Writer
+	compressed io.WriteCloser
+	enc        KVEncoder
+}
+
+func newPartitionWriter(finalName string, codec Codec, bufSize int, compression CompressionName) (*partitionWriter, error) {
+	tmpFile, err := ioutil.TempFile("./", "mr-tmp-*")
+	if err != nil {
+		return nil, fmt.Errorf("create temp file cause error:%w", err)
+	}
+	bw := bufio.NewWriterSize(tmpFile, bufSize)
+	cw, err := compressWriter(bw, compression)
+	if err != nil {
+		tmpFile.Close()
+		os.Remove(tmpFile.Name())
+		return nil, err
+	}
+	return &partitionWriter{finalName: finalName, tmpFile: tmpFile, bw: bw, compressed: cw, enc: codec.NewEncoder(cw)}, nil
+}
+
+func (w *partitionWriter) encode(kv KeyValue) error {
+	if err := w.enc.Encode(&kv); err != nil {
+		return fmt.Errorf("encode error:%w", err)
+	}
+	return nil
+}
+
+func (w *partitionWriter) closeAndRename() error {
+	if err := w.compressed.Close(); err != nil {
+		return fmt.Errorf("close compressed writer for file:%s cause error:%w", w.tmpFile.Name(), err)
+	}
+	if err := w.bw.Flush(); err != nil {
+		return fmt.Errorf("flush file:%s cause error:%w", w.tmpFile.Name(), err)
+	}
+	if err := w.tmpFile.Close(); err != nil {
+		return fmt.Errorf("close file:%s cause error:%w", w.tmpFile.Name(), err)
+	}
+	if err := os.Rename(w.tmpFile.Name(), w.finalName); err != nil {
+		return fmt.Errorf("rename file:%s to:%s cause error:%w", w.tmpFile.Name(), w.finalName, err)
+	}
+	return nil
+}
+
+// discard closes and removes w's temp file without renaming it into
+// place, for use when the map task that was writing it failed partway
+// through.
+func (w *partitionWriter) discard() {
+	w.tmpFile.Close()
+	os.Remove(w.tmpFile.Name())
+}
+
+// writeChunk partitions one chunk's KVs, combines each partition's
+// share with combineF (if any), and streams the result to that
+// partition's writer.
+func writeChunk(
+	kvList []KeyValue,
+	writers []*partitionWriter,
+	partitionF func(key string, nReduce int) int,
+	combineF func(key string, values []string) string,
+	nReduce int,
+) error {
+	byPartition := make(map[int][]int)
+	for i, kv := range kvList {
+		r := partitionF(kv.Key, nReduce)
+		byPartition[r] = append(byPartition[r], i)
+	}
+	for r, indies := range byPartition {
+		for _, kv := range combine(kvList, indies, combineF) {
+			if err := writers[r].encode(kv); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// combine returns the KeyValue pairs (selected from kvList by indies)
+// that should actually be written to a partition's intermediate file.
+// With no combineF it is every pair unchanged. With a combineF, pairs
+// sharing a key are grouped and replaced by the single KeyValue that
+// combineF produces for that key, cutting intermediate file size for
+// aggregations such as word count.
+func combine(kvList []KeyValue, indies []int, combineF func(key string, values []string) string) []KeyValue {
+	if combineF == nil {
+		out := make([]KeyValue, len(indies))
+		for i, index := range indies {
+			out[i] = kvList[index]
+		}
+		return out
+	}
+
+	order := make([]string, 0)
+	values := make(map[string][]string)
+	for _, index := range indies {
+		kvPair := kvList[index]
+		if _, ok := values[kvPair.Key]; !ok {
+			order = append(order, kvPair.Key)
+		}
+		values[kvPair.Key] = append(values[kvPair.Key], kvPair.Value)
+	}
+
+	out := make([]KeyValue, len(order))
+	for i, key := range order {
+		out[i] = KeyValue{key, combineF(key, values[key])}
 	}
+	return out
 }
 
 func ihash(s string) int {
@@ -0,0 +1,133 @@
+package mapreduce
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// TestDoMapStreamingMatchesBuffered checks that the streaming
+// (bounded-memory) path and the buffered path produce the same
+// partitioned output for the same input, just reached differently.
+func TestDoMapStreamingMatchesBuffered(t *testing.T) {
+	const jobName = "test"
+	const nReduce = 3
+	input := "the quick brown\nfox jumps over\nthe lazy dog\nthe fox runs"
+
+	buffered := map[int][]KeyValue{}
+	withTempDir(t, func(dir string) {
+		if err := ioutil.WriteFile("in-0", []byte(input), 0666); err != nil {
+			t.Fatalf("write input: %v", err)
+		}
+		doMap(context.Background(), jobName, 0, Split{File: "in-0"}, nReduce, wordCountMapF, MapOptions{})
+		for r := 0; r < nReduce; r++ {
+			buffered[r] = readPartition(t, JSONCodec{}, jobName, 0, r)
+		}
+	})
+
+	streamed := map[int][]KeyValue{}
+	withTempDir(t, func(dir string) {
+		if err := ioutil.WriteFile("in-0", []byte(input), 0666); err != nil {
+			t.Fatalf("write input: %v", err)
+		}
+		// A tiny chunk size forces several flushes through the
+		// streaming path for this four-line input.
+		doMap(context.Background(), jobName, 0, Split{File: "in-0"}, nReduce, wordCountMapF, MapOptions{Streaming: true, ChunkSize: 16})
+		for r := 0; r < nReduce; r++ {
+			streamed[r] = readPartition(t, JSONCodec{}, jobName, 0, r)
+		}
+	})
+
+	for r := 0; r < nReduce; r++ {
+		a, b := buffered[r], streamed[r]
+		sort.Slice(a, func(i, j int) bool { return a[i].Key < a[j].Key })
+		sort.Slice(b, func(i, j int) bool { return b[i].Key < b[j].Key })
+		if !reflect.DeepEqual(a, b) {
+			t.Fatalf("partition %d: buffered %v != streamed %v", r, a, b)
+		}
+	}
+}
+
+// TestDoMapStreamingLineLongerThanChunkSize checks that a tiny
+// ChunkSize only controls how often mapF is invoked, not the longest
+// line the streaming path can scan: a single line longer than
+// ChunkSize (but well under maxLineSize) must still be read whole.
+func TestDoMapStreamingLineLongerThanChunkSize(t *testing.T) {
+	const jobName = "test"
+	const nReduce = 1
+	line := strings.Repeat("word ", 1000) // ~5KB, far bigger than the 16-byte ChunkSize below
+
+	withTempDir(t, func(dir string) {
+		if err := ioutil.WriteFile("in-0", []byte(line), 0666); err != nil {
+			t.Fatalf("write input: %v", err)
+		}
+		doMap(context.Background(), jobName, 0, Split{File: "in-0"}, nReduce, wordCountMapF, MapOptions{Streaming: true, ChunkSize: 16})
+		kvs := readPartition(t, JSONCodec{}, jobName, 0, 0)
+		if len(kvs) != 1000 {
+			t.Fatalf("got %d KVs, want 1000 (long line must not be truncated or dropped)", len(kvs))
+		}
+	})
+}
+
+// failAfterNCodec is a Codec whose encoders share a single call
+// budget across every partition's writer: the (n+1)th Encode call
+// across all of them fails. Used to simulate an encode failure
+// partway through a map task that already wrote some real output to
+// other partitions.
+type failAfterNCodec struct{ n *int }
+
+func (c failAfterNCodec) NewEncoder(w io.Writer) KVEncoder {
+	return &failAfterNEncoder{enc: JSONCodec{}.NewEncoder(w), n: c.n}
+}
+func (c failAfterNCodec) NewDecoder(r io.Reader) KVDecoder { return JSONCodec{}.NewDecoder(r) }
+
+type failAfterNEncoder struct {
+	enc KVEncoder
+	n   *int
+}
+
+func (e *failAfterNEncoder) Encode(kv *KeyValue) error {
+	if *e.n <= 0 {
+		return fmt.Errorf("simulated encode failure")
+	}
+	*e.n--
+	return e.enc.Encode(kv)
+}
+
+// TestDoMapStreamingDiscardsPartitionsOnError checks that an encode
+// failure partway through the streaming path leaves no intermediate
+// files behind at all, rather than renaming the partial temp files
+// written before the failure into place. A reduce task must never be
+// able to read a partition that its map task did not finish writing.
+func TestDoMapStreamingDiscardsPartitionsOnError(t *testing.T) {
+	const jobName = "test"
+	const nReduce = 2
+	// Two lines land in two different chunks (ChunkSize forces a flush
+	// between them), so the first chunk succeeds and writes real
+	// partition data before the second chunk's encode call fails.
+	input := "aaa\nzzz\n"
+
+	withTempDir(t, func(dir string) {
+		if err := ioutil.WriteFile("in-0", []byte(input), 0666); err != nil {
+			t.Fatalf("write input: %v", err)
+		}
+
+		budget := 1
+		opts := MapOptions{Streaming: true, ChunkSize: 4, Codec: failAfterNCodec{n: &budget}}
+		if err := doMap(context.Background(), jobName, 0, Split{File: "in-0"}, nReduce, wordCountMapF, opts); err == nil {
+			t.Fatal("doMap returned nil error, want the simulated encode failure")
+		}
+
+		for r := 0; r < nReduce; r++ {
+			if _, err := os.Stat(reduceName(jobName, 0, r)); !os.IsNotExist(err) {
+				t.Fatalf("partition %d file exists after a failed map task, want none: err=%v", r, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,260 @@
+package mapreduce
+
+import (
+	"bufio"
+	"context"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// withTempDir chdirs into a fresh temp directory for the duration of
+// fn, so doMap's intermediate files land somewhere scratch and don't
+// collide between subtests.
+func withTempDir(t *testing.T, fn func(dir string)) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "mr-common-map-test")
+	if err != nil {
+		t.Fatalf("create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+	defer os.Chdir(wd)
+
+	fn(dir)
+}
+
+// readPartition decodes every KeyValue out of the intermediate file
+// doMap wrote for (jobName, mapTask, reduceTask) using codec,
+// transparently decompressing it the same way doReduce does.
+func readPartition(t *testing.T, codec Codec, jobName string, mapTask, reduceTask int) []KeyValue {
+	t.Helper()
+
+	f, err := os.Open(reduceName(jobName, mapTask, reduceTask))
+	if err != nil {
+		t.Fatalf("open partition file: %v", err)
+	}
+	defer f.Close()
+
+	r, err := decompress(bufio.NewReader(f))
+	if err != nil {
+		t.Fatalf("decompress partition file: %v", err)
+	}
+
+	var kvs []KeyValue
+	dec := codec.NewDecoder(r)
+	for {
+		var kv KeyValue
+		if err := dec.Decode(&kv); err != nil {
+			break
+		}
+		kvs = append(kvs, kv)
+	}
+	return kvs
+}
+
+func wordCountMapF(filename string, contents string) []KeyValue {
+	var kvs []KeyValue
+	for _, word := range splitWords(contents) {
+		kvs = append(kvs, KeyValue{Key: word, Value: "1"})
+	}
+	return kvs
+}
+
+func splitWords(s string) []string {
+	var words []string
+	var cur []rune
+	flush := func() {
+		if len(cur) > 0 {
+			words = append(words, string(cur))
+			cur = nil
+		}
+	}
+	for _, r := range s {
+		if r == ' ' || r == '\n' || r == '\t' {
+			flush()
+			continue
+		}
+		cur = append(cur, r)
+	}
+	flush()
+	return words
+}
+
+// TestDoMapPartitionHook checks that a custom PartitionF, not just
+// the default ihash-based one, actually decides which intermediate
+// file each key lands in.
+func TestDoMapPartitionHook(t *testing.T) {
+	withTempDir(t, func(dir string) {
+		inFile := "in-0"
+		if err := ioutil.WriteFile(inFile, []byte("a b"), 0666); err != nil {
+			t.Fatalf("write input: %v", err)
+		}
+
+		const jobName = "test"
+		const nReduce = 2
+
+		// Route every key to partition 1 regardless of its hash.
+		opts := MapOptions{PartitionF: func(key string, nReduce int) int { return 1 }}
+		doMap(context.Background(), jobName, 0, Split{File: inFile}, nReduce, wordCountMapF, opts)
+
+		// A partition a map task produced nothing for never gets an
+		// intermediate file at all (see doReduce's handling of that).
+		if _, err := os.Stat(reduceName(jobName, 0, 0)); !os.IsNotExist(err) {
+			t.Fatalf("partition 0 file exists, want none (PartitionF always picks 1): err=%v", err)
+		}
+		got := readPartition(t, JSONCodec{}, jobName, 0, 1)
+		sort.Slice(got, func(i, j int) bool { return got[i].Key < got[j].Key })
+		want := []KeyValue{{Key: "a", Value: "1"}, {Key: "b", Value: "1"}}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("partition 1 got %v, want %v", got, want)
+		}
+	})
+}
+
+// TestDoMapCombineHook checks that a CombineF collapses same-key
+// pairs within a partition before they hit the intermediate file.
+func TestDoMapCombineHook(t *testing.T) {
+	withTempDir(t, func(dir string) {
+		inFile := "in-0"
+		if err := ioutil.WriteFile(inFile, []byte("a a b"), 0666); err != nil {
+			t.Fatalf("write input: %v", err)
+		}
+
+		const jobName = "test"
+		const nReduce = 1
+
+		combined := 0
+		opts := MapOptions{CombineF: func(key string, values []string) string {
+			combined++
+			return values[0]
+		}}
+		doMap(context.Background(), jobName, 0, Split{File: inFile}, nReduce, wordCountMapF, opts)
+
+		if combined == 0 {
+			t.Fatal("CombineF was never called")
+		}
+		got := readPartition(t, JSONCodec{}, jobName, 0, 0)
+		if len(got) != 2 {
+			t.Fatalf("got %d combined KVs, want 2 (one per distinct key): %v", len(got), got)
+		}
+	})
+}
+
+// TestDoMapCombineHookShrinksIntermediateFile checks the actual
+// payoff of a CombineF: a word-count-style summing combiner should
+// make the intermediate file for a partition with many repeated keys
+// smaller, not just logically equivalent, than writing every raw
+// KeyValue.
+func TestDoMapCombineHookShrinksIntermediateFile(t *testing.T) {
+	const jobName = "test"
+	const nReduce = 1
+	input := strings.Repeat("the ", 500) // one key, 500 raw pairs
+
+	var rawSize, combinedSize int64
+	withTempDir(t, func(dir string) {
+		if err := ioutil.WriteFile("in-0", []byte(input), 0666); err != nil {
+			t.Fatalf("write input: %v", err)
+		}
+		doMap(context.Background(), jobName, 0, Split{File: "in-0"}, nReduce, wordCountMapF, MapOptions{})
+		info, err := os.Stat(reduceName(jobName, 0, 0))
+		if err != nil {
+			t.Fatalf("stat raw partition: %v", err)
+		}
+		rawSize = info.Size()
+	})
+
+	withTempDir(t, func(dir string) {
+		if err := ioutil.WriteFile("in-0", []byte(input), 0666); err != nil {
+			t.Fatalf("write input: %v", err)
+		}
+		sumCombine := func(key string, values []string) string { return strconv.Itoa(len(values)) }
+		doMap(context.Background(), jobName, 0, Split{File: "in-0"}, nReduce, wordCountMapF, MapOptions{CombineF: sumCombine})
+		info, err := os.Stat(reduceName(jobName, 0, 0))
+		if err != nil {
+			t.Fatalf("stat combined partition: %v", err)
+		}
+		combinedSize = info.Size()
+	})
+
+	if combinedSize >= rawSize {
+		t.Fatalf("combined partition (%d bytes) not smaller than raw partition (%d bytes)", combinedSize, rawSize)
+	}
+}
+
+// TestDoMapReturnsErrorInsteadOfPanicking checks that an unreadable
+// input file makes doMap return an error rather than panic, so
+// callers embedding this package as a library can decide how to
+// react instead of the whole process going down.
+func TestDoMapReturnsErrorInsteadOfPanicking(t *testing.T) {
+	withTempDir(t, func(dir string) {
+		err := doMap(context.Background(), "test", 0, Split{File: "does-not-exist"}, 1, wordCountMapF, MapOptions{})
+		if err == nil {
+			t.Fatal("doMap returned nil error for a missing input file")
+		}
+	})
+}
+
+// TestDoReduceReturnsErrorInsteadOfPanicking checks the same for
+// doReduce: an output path it cannot create should surface as an
+// error, not a panic.
+func TestDoReduceReturnsErrorInsteadOfPanicking(t *testing.T) {
+	withTempDir(t, func(dir string) {
+		// A directory can never be created as a regular file, so
+		// os.Create(outFile) is guaranteed to fail.
+		if err := os.Mkdir("out-is-a-dir", 0777); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		err := doReduce(context.Background(), "test", 0, "out-is-a-dir", 0, func(string, []string) string { return "" }, JSONCodec{}, false)
+		if err == nil {
+			t.Fatal("doReduce returned nil error for an uncreatable output file")
+		}
+	})
+}
+
+// TestDoMapRespectsCancelledContext checks that doMap returns the
+// context's error immediately, without touching the filesystem, when
+// ctx is already cancelled before the task starts.
+func TestDoMapRespectsCancelledContext(t *testing.T) {
+	withTempDir(t, func(dir string) {
+		if err := ioutil.WriteFile("in-0", []byte("a b"), 0666); err != nil {
+			t.Fatalf("write input: %v", err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := doMap(ctx, "test", 0, Split{File: "in-0"}, 1, wordCountMapF, MapOptions{})
+		if err != context.Canceled {
+			t.Fatalf("doMap with a cancelled ctx: got %v, want context.Canceled", err)
+		}
+		if _, err := os.Stat(reduceName("test", 0, 0)); !os.IsNotExist(err) {
+			t.Fatalf("partition file exists for a task that never ran: err=%v", err)
+		}
+	})
+}
+
+// TestDoReduceRespectsCancelledContext checks the same for doReduce.
+func TestDoReduceRespectsCancelledContext(t *testing.T) {
+	withTempDir(t, func(dir string) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := doReduce(ctx, "test", 0, "out-0", 0, func(string, []string) string { return "" }, JSONCodec{}, false)
+		if err != context.Canceled {
+			t.Fatalf("doReduce with a cancelled ctx: got %v, want context.Canceled", err)
+		}
+	})
+}
@@ -0,0 +1,162 @@
+package mapreduce
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// doReduce manages one reduce task: it reads the nMap intermediate
+// files that the map phase produced for reduceTask (decoding them
+// with codec, the same Codec the map task was given), groups the
+// values by key, calls the user-defined reduce function (reduceF)
+// once per key, and writes the results as JSON to outFile. The final
+// output is always JSON regardless of codec. It returns an error
+// instead of panicking on any IO failure, so callers embedding this
+// package as a library can retry, skip, or abort the task themselves.
+// It also checks ctx before starting and before reading each
+// intermediate file, returning ctx.Err() as soon as ctx is done.
+//
+// secondarySort must match the MapOptions.SecondarySort the map phase
+// used: every key is then treated as a CompositeKey(primary,
+// secondary), keys group by primary instead of the whole composite
+// key, and reduceF's values arrive sorted by secondary instead of in
+// arrival order.
+func doReduce(
+	ctx context.Context,
+	jobName string, // the name of the whole MapReduce job
+	reduceTask int, // which reduce task this is
+	outFile string,
+	nMap int, // the number of map tasks that were run
+	reduceF func(key string, values []string) string,
+	codec Codec, // how the intermediate files were serialized; defaults to JSONCodec when nil
+	secondarySort bool, // group by a CompositeKey's primary and sort each primary's values by secondary; see MapOptions.SecondarySort
+) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if codec == nil {
+		codec = JSONCodec{}
+	}
+
+	kvMap := make(map[string][]string)
+	var secondaries map[string][]string
+	if secondarySort {
+		secondaries = make(map[string][]string)
+	}
+
+	for m := 0; m < nMap; m++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		fileName := reduceName(jobName, m, reduceTask)
+		var err error
+		if secondarySort {
+			err = readPartitionIntoSecondary(kvMap, secondaries, fileName, codec)
+		} else {
+			err = readPartitionInto(kvMap, fileName, codec)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if secondarySort {
+		for key, values := range kvMap {
+			sort.Sort(&secondarySortable{secs: secondaries[key], values: values})
+		}
+	}
+
+	keys := make([]string, 0, len(kvMap))
+	for k := range kvMap {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	outFd, err := os.Create(outFile)
+	if err != nil {
+		return fmt.Errorf("create file:%s cause error:%w", outFile, err)
+	}
+	defer outFd.Close()
+
+	enc := json.NewEncoder(outFd)
+	for _, k := range keys {
+		if err := enc.Encode(KeyValue{k, reduceF(k, kvMap[k])}); err != nil {
+			return fmt.Errorf("encode error:%w", err)
+		}
+	}
+	return nil
+}
+
+// readPartitionInto decodes every KeyValue in fileName and appends it
+// to kvMap. A missing file is not an error: doMap's buffered path
+// never creates a (mapTask, reduceTask) intermediate file unless it
+// actually produced a key destined for that partition, so a missing
+// file just means that map task had nothing for reduceTask. (The
+// streaming and spill paths create every partition's file, empty or
+// not.) fileName is
+// transparently decompressed via decompress, so it works whether or
+// not the map task's MapOptions.Compression compressed it; doReduce
+// itself never needs to know which.
+func readPartitionInto(kvMap map[string][]string, fileName string, codec Codec) error {
+	inFile, err := os.Open(fileName)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("open file:%s cause error:%w", fileName, err)
+	}
+	defer inFile.Close()
+
+	r, err := decompress(bufio.NewReader(inFile))
+	if err != nil {
+		return fmt.Errorf("decompress file:%s cause error:%w", fileName, err)
+	}
+
+	dec := codec.NewDecoder(r)
+	for {
+		var kv KeyValue
+		if err := dec.Decode(&kv); err != nil {
+			break
+		}
+		kvMap[kv.Key] = append(kvMap[kv.Key], kv.Value)
+	}
+	return nil
+}
+
+// readPartitionIntoSecondary is readPartitionInto's SecondarySort
+// variant: each entry's key is split into its CompositeKey primary and
+// secondary components, the value is appended to kvMap under the
+// primary, and the secondary is appended to secondaries under the same
+// primary in lockstep, so doReduce can later sort each primary's
+// values into secondary order.
+func readPartitionIntoSecondary(kvMap map[string][]string, secondaries map[string][]string, fileName string, codec Codec) error {
+	inFile, err := os.Open(fileName)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("open file:%s cause error:%w", fileName, err)
+	}
+	defer inFile.Close()
+
+	r, err := decompress(bufio.NewReader(inFile))
+	if err != nil {
+		return fmt.Errorf("decompress file:%s cause error:%w", fileName, err)
+	}
+
+	dec := codec.NewDecoder(r)
+	for {
+		var kv KeyValue
+		if err := dec.Decode(&kv); err != nil {
+			break
+		}
+		primary, secondary := splitCompositeKey(kv.Key)
+		kvMap[primary] = append(kvMap[primary], kv.Value)
+		secondaries[primary] = append(secondaries[primary], secondary)
+	}
+	return nil
+}
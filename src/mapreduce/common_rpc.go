@@ -0,0 +1,87 @@
+package mapreduce
+
+import (
+	"fmt"
+	"log"
+	"net/rpc"
+	"os"
+)
+
+// TaskType distinguishes the kinds of task a coordinator can hand a
+// worker.
+type TaskType int
+
+const (
+	MapTask TaskType = iota
+	ReduceTask
+	WaitTask // no task is ready yet; the worker should retry shortly
+	ExitTask // the job is done; the worker should shut down
+)
+
+// GetTaskArgs is empty: a worker asking for a task identifies no
+// state of its own, it just wants whatever is next.
+type GetTaskArgs struct{}
+
+// GetTaskReply describes the task (if any) assigned to the caller.
+type GetTaskReply struct {
+	Type       TaskType
+	TaskNum    int
+	JobName    string
+	File       string // input file; only set when Type == MapTask
+	SplitStart int64  // byte range of File this map task reads; SplitStart == SplitEnd == 0 means the whole file
+	SplitEnd   int64
+	NMap       int
+	NReduce    int
+	Opts       JobOptions // job-wide codec/streaming settings every worker must agree on
+}
+
+// JobOptions carries the job-wide settings a Coordinator hands every
+// worker over RPC: which Codec to use for intermediate files, whether
+// map tasks should use doMap's bounded-memory streaming path, and how
+// map tasks should compress their intermediate files. The zero value
+// selects JSONCodec, the whole-file path, and no compression.
+type JobOptions struct {
+	Codec         CodecName
+	Streaming     bool
+	ChunkSize     int
+	SplitSize     int64           // maximum bytes per map task's input split; 0 gives one split per file
+	Compression   CompressionName // how map tasks compress intermediate files; reduce tasks need no matching setting, since decompress sniffs the format
+	SecondarySort bool            // treat every key as a CompositeKey(primary, secondary); see MapOptions.SecondarySort
+}
+
+// ReportTaskArgs tells the coordinator that a worker finished (or
+// failed) executing the given task. Counters holds whatever the task's
+// mapF/reduceF reported via Count while it ran; the coordinator merges
+// it into the job's running totals when Success is true.
+type ReportTaskArgs struct {
+	Type     TaskType
+	TaskNum  int
+	Success  bool
+	Counters map[string]int64
+}
+
+type ReportTaskReply struct{}
+
+// coordinatorSock returns a per-user UNIX-domain socket path so that
+// mrworker can find the mrcoordinator it should talk to.
+func coordinatorSock() string {
+	return fmt.Sprintf("/var/tmp/mr-coordinator-%d", os.Getuid())
+}
+
+// call sends an RPC to the coordinator and waits for the reply. It
+// returns false on any dial or call error, which callers treat as "the
+// coordinator is gone."
+func call(rpcname string, args interface{}, reply interface{}) bool {
+	c, err := rpc.DialHTTP("unix", coordinatorSock())
+	if err != nil {
+		log.Println("dialing:", err)
+		return false
+	}
+	defer c.Close()
+
+	if err := c.Call(rpcname, args, reply); err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
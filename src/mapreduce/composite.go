@@ -0,0 +1,61 @@
+package mapreduce
+
+import "strings"
+
+// compositeKeySeparator joins a composite key's primary and secondary
+// components. It is a control byte legitimate key components are
+// vanishingly unlikely to contain, the same tradeoff saltSeparator
+// already makes.
+const compositeKeySeparator = "\x1f"
+
+// CompositeKey builds a key of the form (primary, secondary) for a
+// MapOptions.SecondarySort job: mapF calls this instead of emitting a
+// plain string key, so that doMap partitions solely on primary --
+// every value for a primary lands in the same reduce task, regardless
+// of its secondary -- while doReduce still delivers reduceF each
+// primary's values sorted by secondary. This enables, for example,
+// sessionizing a primary key's events in timestamp order without the
+// reducer buffering and sorting them itself.
+func CompositeKey(primary, secondary string) string {
+	return primary + compositeKeySeparator + secondary
+}
+
+// splitCompositeKey splits a key CompositeKey built back into its
+// primary and secondary components. A key with no separator (never
+// built by CompositeKey) is treated as its own primary with an empty
+// secondary, so a stray plain key in a SecondarySort job degrades
+// gracefully instead of panicking.
+func splitCompositeKey(key string) (primary, secondary string) {
+	i := strings.Index(key, compositeKeySeparator)
+	if i < 0 {
+		return key, ""
+	}
+	return key[:i], key[i+1:]
+}
+
+// secondarySortPartitionF wraps partitionF so it partitions solely on
+// a composite key's primary component, ignoring the secondary. doMap
+// uses this in place of opts.PartitionF when opts.SecondarySort is
+// set.
+func secondarySortPartitionF(partitionF func(key string, nReduce int) int) func(string, int) int {
+	return func(key string, nReduce int) int {
+		primary, _ := splitCompositeKey(key)
+		return partitionF(primary, nReduce)
+	}
+}
+
+// secondarySortable sorts values in place by their parallel secondary
+// components, keeping secs and values in lockstep. doReduce uses it to
+// deliver reduceF a SecondarySort primary key's values in secondary
+// order.
+type secondarySortable struct {
+	secs   []string
+	values []string
+}
+
+func (s *secondarySortable) Len() int           { return len(s.secs) }
+func (s *secondarySortable) Less(i, j int) bool { return s.secs[i] < s.secs[j] }
+func (s *secondarySortable) Swap(i, j int) {
+	s.secs[i], s.secs[j] = s.secs[j], s.secs[i]
+	s.values[i], s.values[j] = s.values[j], s.values[i]
+}
@@ -0,0 +1,95 @@
+package mapreduce
+
+import (
+	"context"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestCompositeKeyRoundTrips(t *testing.T) {
+	key := CompositeKey("session-1", "00042")
+	primary, secondary := splitCompositeKey(key)
+	if primary != "session-1" || secondary != "00042" {
+		t.Fatalf("splitCompositeKey(%q) = (%q, %q), want (\"session-1\", \"00042\")", key, primary, secondary)
+	}
+}
+
+func TestSplitCompositeKeyWithoutSeparator(t *testing.T) {
+	primary, secondary := splitCompositeKey("plain")
+	if primary != "plain" || secondary != "" {
+		t.Fatalf("splitCompositeKey(\"plain\") = (%q, %q), want (\"plain\", \"\")", primary, secondary)
+	}
+}
+
+func TestSecondarySortPartitionFIgnoresSecondary(t *testing.T) {
+	partitionF := secondarySortPartitionF(func(key string, nReduce int) int {
+		if key != "session-1" {
+			t.Fatalf("partitionF got key %q, want the primary component only", key)
+		}
+		return 0
+	})
+	partitionF(CompositeKey("session-1", "9"), 4)
+	partitionF(CompositeKey("session-1", "1"), 4)
+}
+
+func TestSecondarySortableSortsValuesBySecondary(t *testing.T) {
+	s := &secondarySortable{
+		secs:   []string{"3", "1", "2"},
+		values: []string{"c", "a", "b"},
+	}
+	sort.Sort(s)
+	if got := strings.Join(s.values, ""); got != "abc" {
+		t.Fatalf("sorted values = %q, want \"abc\"", got)
+	}
+	if got := strings.Join(s.secs, ""); got != "123" {
+		t.Fatalf("sorted secs = %q, want \"123\"", got)
+	}
+}
+
+// TestSequentialSecondarySortDeliversValuesInOrder runs a full
+// Sequential job whose mapF emits a single primary key's events with
+// out-of-order timestamps as the secondary component, and checks that
+// reduceF sees them already sorted into timestamp order -- the
+// sessionization use case SecondarySort exists for.
+func TestSequentialSecondarySortDeliversValuesInOrder(t *testing.T) {
+	const jobName = "test"
+	const nReduce = 4
+
+	withTempDir(t, func(dir string) {
+		if err := ioutil.WriteFile("in-0", []byte(""), 0666); err != nil {
+			t.Fatalf("write input: %v", err)
+		}
+
+		events := []struct{ ts, action string }{
+			{"3", "click"},
+			{"1", "login"},
+			{"2", "view"},
+		}
+		sessionMapF := func(filename, contents string) []KeyValue {
+			kvs := make([]KeyValue, len(events))
+			for i, e := range events {
+				kvs[i] = KeyValue{Key: CompositeKey("session-1", e.ts), Value: e.action}
+			}
+			return kvs
+		}
+		var got string
+		joinReduceF := func(key string, values []string) string {
+			if key != "session-1" {
+				t.Fatalf("reduceF got key %q, want \"session-1\"", key)
+			}
+			got = strings.Join(values, ",")
+			return got
+		}
+
+		opts := MapOptions{SecondarySort: true}
+		if err := Sequential(context.Background(), jobName, []string{"in-0"}, nReduce, sessionMapF, joinReduceF, opts); err != nil {
+			t.Fatalf("Sequential: %v", err)
+		}
+
+		if got != "login,view,click" {
+			t.Fatalf("reduceF saw values %q, want \"login,view,click\"", got)
+		}
+	})
+}
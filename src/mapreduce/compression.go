@@ -0,0 +1,116 @@
+package mapreduce
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// splittable reports whether f can be divided into several byte-range
+// Splits. Compressed formats can only be decoded from the start of
+// the stream, so a file with a .gz, .bz2 or .zst extension is never
+// split regardless of opts.SplitSize; it is always handed to exactly
+// one map task, the same as doMap's original one-file-per-task
+// behavior. The check goes by extension only: a compressed file
+// without one of these extensions is split like any other file.
+func splittable(f string) bool {
+	switch strings.ToLower(filepath.Ext(f)) {
+	case ".gz", ".bz2", ".zst":
+		return false
+	default:
+		return true
+	}
+}
+
+// gzipMagic, bzip2Magic and zstdMagic are the byte sequences compressed
+// input files start with; decompress sniffs these instead of relying
+// on the file extension, so a renamed or extensionless compressed
+// file still decompresses transparently.
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	bzip2Magic = []byte("BZh")
+	zstdMagic  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// CompressionName selects how doMap compresses the intermediate files
+// it writes; doReduce needs no matching setting to read them back,
+// since it decompresses every intermediate file with decompress, which
+// sniffs the format from the file's own content.
+type CompressionName string
+
+const (
+	NoCompression     CompressionName = ""
+	GzipCompression   CompressionName = "gzip"
+	SnappyCompression CompressionName = "snappy"
+	ZstdCompression   CompressionName = "zstd"
+)
+
+// compressWriter wraps w so that data written through the result is
+// compressed according to name before reaching w; the caller must
+// Close the result (which does not close w) to flush the compressor's
+// trailing bytes. NoCompression, the zero value, returns w wrapped in
+// a no-op Closer.
+//
+// SnappyCompression and ZstdCompression are rejected outright: this
+// repo takes no third-party dependencies and the standard library has
+// neither a snappy nor a zstd writer, so claiming to support them
+// would silently produce intermediate files decompress can't read
+// back instead of failing loudly at the point the choice was made.
+func compressWriter(w io.Writer, name CompressionName) (io.WriteCloser, error) {
+	switch name {
+	case NoCompression:
+		return nopWriteCloser{w}, nil
+	case GzipCompression:
+		return gzip.NewWriter(w), nil
+	case SnappyCompression, ZstdCompression:
+		return nil, fmt.Errorf("%s intermediate-file compression is not supported: this build takes no third-party dependencies and the standard library has no %s writer", name, name)
+	default:
+		return nil, fmt.Errorf("unknown compression %q", name)
+	}
+}
+
+// nopWriteCloser adapts an io.Writer that needs no flushing or closing
+// of its own into an io.WriteCloser, for compressWriter's
+// NoCompression case.
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+// decompress wraps r with a gzip or bzip2 reader if r's first bytes
+// match one of those formats' magic numbers, so doMap and
+// doMapStreaming can read compressed input the same way they read
+// plain text. r must support Peek (as *bufio.Reader does) so sniffing
+// the magic number doesn't consume any bytes the decompressor itself
+// needs to see.
+//
+// zstd-compressed input is detected but not decompressible: the Go
+// standard library has no zstd reader, and this repo takes no
+// third-party dependencies, so a .zst (or zstd-magic) input fails
+// doMap with a clear error instead of silently reading compressed
+// garbage as if it were text.
+func decompress(r *bufio.Reader) (io.Reader, error) {
+	magic, err := r.Peek(4)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("peek input cause error:%w", err)
+	}
+
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("gzip input cause error:%w", err)
+		}
+		return gz, nil
+	case bytes.HasPrefix(magic, bzip2Magic):
+		return bzip2.NewReader(r), nil
+	case bytes.HasPrefix(magic, zstdMagic):
+		return nil, fmt.Errorf("input is zstd-compressed, which this build cannot decompress (no zstd support in the standard library)")
+	default:
+		return r, nil
+	}
+}
@@ -0,0 +1,109 @@
+package mapreduce
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"testing"
+)
+
+func TestSplittable(t *testing.T) {
+	cases := map[string]bool{
+		"in-0":     true,
+		"log.txt":  true,
+		"in-0.gz":  false,
+		"in-0.bz2": false,
+		"data.zst": false,
+		"data.ZST": false,
+	}
+	for f, want := range cases {
+		if got := splittable(f); got != want {
+			t.Errorf("splittable(%q) = %v, want %v", f, got, want)
+		}
+	}
+}
+
+// TestDoMapDecompressesGzipInput checks that doMap transparently
+// decompresses a gzip input file instead of handing mapF the raw
+// compressed bytes.
+func TestDoMapDecompressesGzipInput(t *testing.T) {
+	const jobName = "test"
+	const nReduce = 1
+
+	withTempDir(t, func(dir string) {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		if _, err := gz.Write([]byte("the quick brown fox")); err != nil {
+			t.Fatalf("gzip write: %v", err)
+		}
+		if err := gz.Close(); err != nil {
+			t.Fatalf("gzip close: %v", err)
+		}
+		if err := ioutil.WriteFile("in-0.gz", buf.Bytes(), 0666); err != nil {
+			t.Fatalf("write input: %v", err)
+		}
+
+		if err := doMap(context.Background(), jobName, 0, Split{File: "in-0.gz"}, nReduce, wordCountMapF, MapOptions{}); err != nil {
+			t.Fatalf("doMap: %v", err)
+		}
+		kvs := readPartition(t, JSONCodec{}, jobName, 0, 0)
+		if len(kvs) != 4 {
+			t.Fatalf("got %d KVs, want 4 (one per word in the decompressed input)", len(kvs))
+		}
+	})
+}
+
+// TestDoMapGzipCompressesIntermediateFile checks that
+// MapOptions.Compression: GzipCompression makes doMap write a
+// gzip-magic-prefixed intermediate file, and that doReduce (via
+// readPartition, which decompresses the same way doReduce does) reads
+// it back correctly despite doReduce never being told which
+// compression, if any, was used.
+func TestDoMapGzipCompressesIntermediateFile(t *testing.T) {
+	const jobName = "test"
+	const nReduce = 1
+
+	withTempDir(t, func(dir string) {
+		if err := ioutil.WriteFile("in-0", []byte("the quick brown fox"), 0666); err != nil {
+			t.Fatalf("write input: %v", err)
+		}
+
+		opts := MapOptions{Compression: GzipCompression}
+		if err := doMap(context.Background(), jobName, 0, Split{File: "in-0"}, nReduce, wordCountMapF, opts); err != nil {
+			t.Fatalf("doMap: %v", err)
+		}
+
+		raw, err := ioutil.ReadFile(reduceName(jobName, 0, 0))
+		if err != nil {
+			t.Fatalf("read partition file: %v", err)
+		}
+		if !bytes.HasPrefix(raw, gzipMagic) {
+			t.Fatalf("partition file does not start with the gzip magic bytes: %x", raw[:4])
+		}
+
+		kvs := readPartition(t, JSONCodec{}, jobName, 0, 0)
+		if len(kvs) != 4 {
+			t.Fatalf("got %d KVs, want 4 (one per word)", len(kvs))
+		}
+	})
+}
+
+// TestDoMapUnsupportedCompressionErrors checks that asking for a
+// compression this build cannot write fails doMap outright, instead of
+// silently writing an intermediate file doReduce can't decompress.
+func TestDoMapUnsupportedCompressionErrors(t *testing.T) {
+	for _, compression := range []CompressionName{SnappyCompression, ZstdCompression} {
+		withTempDir(t, func(dir string) {
+			if err := ioutil.WriteFile("in-0", []byte("the quick brown fox"), 0666); err != nil {
+				t.Fatalf("write input: %v", err)
+			}
+
+			opts := MapOptions{Compression: compression}
+			err := doMap(context.Background(), "test", 0, Split{File: "in-0"}, 1, wordCountMapF, opts)
+			if err == nil {
+				t.Fatalf("doMap with Compression: %q returned nil error, want an unsupported-compression error", compression)
+			}
+		})
+	}
+}
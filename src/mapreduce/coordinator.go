@@ -0,0 +1,229 @@
+package mapreduce
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/http"
+	"net/rpc"
+	"os"
+	"sync"
+	"time"
+)
+
+// TaskTimeout is how long the coordinator waits for a worker to
+// report a task done before assuming the worker died and putting the
+// task back up for grabs.
+const TaskTimeout = 10 * time.Second
+
+type taskState int
+
+const (
+	NotStarted taskState = iota
+	Executing
+	Done
+)
+
+type task struct {
+	split Split // input split; only meaningful for map tasks
+	state taskState
+}
+
+// Coordinator schedules the map and reduce tasks of a MapReduce job
+// across remote workers that connect over RPC. Unlike Master, which
+// runs every task itself, Coordinator tolerates worker crashes: any
+// task still Executing after TaskTimeout is reassigned.
+type Coordinator struct {
+	mu sync.Mutex
+
+	ctx     context.Context
+	jobName string
+	nMap    int
+	nReduce int
+	opts    JobOptions
+
+	mapTasks    []task
+	reduceTasks []task
+	mapDone     int
+	reduceDone  int
+	counters    *Counters
+
+	rpcServer *rpc.Server
+}
+
+// NewCoordinator creates a Coordinator for jobName over files, starts
+// serving RPCs in the background, and returns immediately. Callers
+// poll Done to learn when the job has finished. opts is handed to
+// every worker over RPC (see JobOptions) so the whole job agrees on
+// the intermediate-file codec and whether map tasks should stream.
+// opts.SplitSize, if set, divides any file bigger than it into several
+// map tasks instead of exactly one; NewCoordinator stats every file to
+// plan those splits and fatals if a file can't be stat-ed, the same
+// way server() fatals on a listen failure it can't recover from.
+// Cancelling ctx makes GetTask reply ExitTask to every worker from
+// then on, so a cancelled job's workers shut down instead of waiting
+// forever for tasks that will never be handed out.
+func NewCoordinator(ctx context.Context, jobName string, files []string, nReduce int, opts JobOptions) *Coordinator {
+	splits, err := planSplits(files, opts.SplitSize)
+	if err != nil {
+		log.Fatal("plan splits error:", err)
+	}
+
+	c := &Coordinator{
+		ctx:         ctx,
+		jobName:     jobName,
+		nMap:        len(splits),
+		nReduce:     nReduce,
+		opts:        opts,
+		mapTasks:    make([]task, len(splits)),
+		reduceTasks: make([]task, nReduce),
+		counters:    NewCounters(),
+	}
+	for i, s := range splits {
+		c.mapTasks[i] = task{split: s}
+	}
+
+	c.server()
+	return c
+}
+
+// GetTask hands the calling worker its next map or reduce task. It
+// replies with WaitTask when every remaining task is already
+// executing, and ExitTask once the whole job is done.
+func (c *Coordinator) GetTask(args *GetTaskArgs, reply *GetTaskReply) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.ctx.Err() != nil {
+		reply.Type = ExitTask
+		return nil
+	}
+
+	reply.JobName = c.jobName
+	reply.NMap = c.nMap
+	reply.NReduce = c.nReduce
+	reply.Opts = c.opts
+
+	if c.mapDone < c.nMap {
+		if i, ok := c.nextNotStarted(c.mapTasks); ok {
+			c.mapTasks[i].state = Executing
+			reply.Type = MapTask
+			reply.TaskNum = i
+			reply.File = c.mapTasks[i].split.File
+			reply.SplitStart = c.mapTasks[i].split.Start
+			reply.SplitEnd = c.mapTasks[i].split.End
+			go c.reclaimIfStuck(MapTask, i)
+			return nil
+		}
+		reply.Type = WaitTask
+		return nil
+	}
+
+	if c.reduceDone < c.nReduce {
+		if i, ok := c.nextNotStarted(c.reduceTasks); ok {
+			c.reduceTasks[i].state = Executing
+			reply.Type = ReduceTask
+			reply.TaskNum = i
+			go c.reclaimIfStuck(ReduceTask, i)
+			return nil
+		}
+		reply.Type = WaitTask
+		return nil
+	}
+
+	reply.Type = ExitTask
+	return nil
+}
+
+func (c *Coordinator) nextNotStarted(tasks []task) (int, bool) {
+	for i := range tasks {
+		if tasks[i].state == NotStarted {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// reclaimIfStuck puts taskNum back up for grabs if it is still
+// Executing TaskTimeout after being handed out.
+func (c *Coordinator) reclaimIfStuck(t TaskType, taskNum int) {
+	<-time.After(TaskTimeout)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	tasks := c.mapTasks
+	if t == ReduceTask {
+		tasks = c.reduceTasks
+	}
+	if tasks[taskNum].state == Executing {
+		tasks[taskNum].state = NotStarted
+	}
+}
+
+// ReportTask records the outcome of a task a worker was given. A
+// failed task goes back to NotStarted; a successful one is marked
+// Done unless TaskTimeout already reclaimed it and someone else
+// finished it first, and its Counters are merged into the job's
+// running totals.
+func (c *Coordinator) ReportTask(args *ReportTaskArgs, reply *ReportTaskReply) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	tasks := c.mapTasks
+	done := &c.mapDone
+	if args.Type == ReduceTask {
+		tasks = c.reduceTasks
+		done = &c.reduceDone
+	}
+
+	if !args.Success {
+		if tasks[args.TaskNum].state == Executing {
+			tasks[args.TaskNum].state = NotStarted
+		}
+		return nil
+	}
+
+	if tasks[args.TaskNum].state != Done {
+		tasks[args.TaskNum].state = Done
+		*done++
+		c.counters.Merge(args.Counters)
+	}
+	return nil
+}
+
+// Done reports whether every map and reduce task has completed.
+func (c *Coordinator) Done() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.mapDone == c.nMap && c.reduceDone == c.nReduce
+}
+
+// Counters returns a snapshot of every count reported by workers so
+// far, aggregated across every task that has finished. Safe to call
+// at any time, including before the job is Done.
+func (c *Coordinator) Counters() map[string]int64 {
+	return c.counters.Snapshot()
+}
+
+// server registers the Coordinator's RPC methods on a Coordinator-
+// owned rpc.Server and http.ServeMux (never the rpc/http package
+// defaults, which only ever hold one "Coordinator" registration at a
+// time and would panic on a second one in the same process), then
+// starts accepting connections on coordinatorSock() in the background.
+func (c *Coordinator) server() {
+	c.rpcServer = rpc.NewServer()
+	if err := c.rpcServer.Register(c); err != nil {
+		log.Fatal("register error:", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(rpc.DefaultRPCPath, c.rpcServer)
+
+	os.Remove(coordinatorSock())
+	l, err := net.Listen("unix", coordinatorSock())
+	if err != nil {
+		log.Fatal("listen error:", err)
+	}
+	go http.Serve(l, mux)
+}
@@ -0,0 +1,85 @@
+package mapreduce
+
+import (
+	"context"
+	"testing"
+)
+
+// TestNewCoordinatorTwiceDoesNotPanic is a regression test for
+// server() registering on the package-level rpc/http defaults: that
+// version panicked with "multiple registrations for /_goRPC_" the
+// second time NewCoordinator ran in one process, and even without a
+// panic the second Coordinator's methods never actually replaced the
+// first's on the shared mux. Each Coordinator now owns its rpc.Server
+// and http.ServeMux, so building two in the same process must work
+// and each must answer its own GetTask correctly.
+func TestNewCoordinatorTwiceDoesNotPanic(t *testing.T) {
+	c1 := NewCoordinator(context.Background(), "job1", []string{"in-0"}, 1, JobOptions{})
+	c2 := NewCoordinator(context.Background(), "job2", []string{"in-a", "in-b"}, 2, JobOptions{})
+
+	reply1 := GetTaskReply{}
+	if err := c1.GetTask(&GetTaskArgs{}, &reply1); err != nil {
+		t.Fatalf("c1.GetTask: %v", err)
+	}
+	if reply1.JobName != "job1" || reply1.Type != MapTask {
+		t.Fatalf("c1.GetTask: got %+v, want job1 MapTask", reply1)
+	}
+
+	reply2 := GetTaskReply{}
+	if err := c2.GetTask(&GetTaskArgs{}, &reply2); err != nil {
+		t.Fatalf("c2.GetTask: %v", err)
+	}
+	if reply2.JobName != "job2" || reply2.Type != MapTask {
+		t.Fatalf("c2.GetTask: got %+v, want job2 MapTask", reply2)
+	}
+}
+
+// TestCoordinatorReportTaskReassignsOnFailure checks the
+// GetTask/ReportTask bookkeeping a worker drives directly (without
+// going over RPC): a failed task goes back to NotStarted and is handed
+// out again, and the job isn't Done until every task succeeds.
+func TestCoordinatorReportTaskReassignsOnFailure(t *testing.T) {
+	c := NewCoordinator(context.Background(), "job", []string{"in-0"}, 1, JobOptions{})
+
+	reply := GetTaskReply{}
+	if err := c.GetTask(&GetTaskArgs{}, &reply); err != nil {
+		t.Fatalf("GetTask: %v", err)
+	}
+	if reply.Type != MapTask {
+		t.Fatalf("got %+v, want a MapTask", reply)
+	}
+
+	if err := c.ReportTask(&ReportTaskArgs{Type: MapTask, TaskNum: reply.TaskNum, Success: false}, &ReportTaskReply{}); err != nil {
+		t.Fatalf("ReportTask(failure): %v", err)
+	}
+	if c.Done() {
+		t.Fatal("Done() true after a reported failure")
+	}
+
+	retry := GetTaskReply{}
+	if err := c.GetTask(&GetTaskArgs{}, &retry); err != nil {
+		t.Fatalf("GetTask (retry): %v", err)
+	}
+	if retry.Type != MapTask || retry.TaskNum != reply.TaskNum {
+		t.Fatalf("got %+v, want the failed map task reassigned", retry)
+	}
+
+	if err := c.ReportTask(&ReportTaskArgs{Type: MapTask, TaskNum: retry.TaskNum, Success: true}, &ReportTaskReply{}); err != nil {
+		t.Fatalf("ReportTask(success): %v", err)
+	}
+
+	reduce := GetTaskReply{}
+	if err := c.GetTask(&GetTaskArgs{}, &reduce); err != nil {
+		t.Fatalf("GetTask (reduce): %v", err)
+	}
+	if reduce.Type != ReduceTask {
+		t.Fatalf("got %+v, want a ReduceTask once the only map task is done", reduce)
+	}
+
+	if err := c.ReportTask(&ReportTaskArgs{Type: ReduceTask, TaskNum: reduce.TaskNum, Success: true}, &ReportTaskReply{}); err != nil {
+		t.Fatalf("ReportTask(reduce success): %v", err)
+	}
+	if !c.Done() {
+		t.Fatal("Done() false after every map and reduce task succeeded")
+	}
+}
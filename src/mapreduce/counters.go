@@ -0,0 +1,78 @@
+package mapreduce
+
+import "sync"
+
+// Counters accumulates named integer counts, such as counts of
+// malformed input records, that a running map or reduce task reports
+// alongside its result. It is safe for concurrent use, since
+// Sequential runs many map and reduce tasks concurrently in one
+// process.
+type Counters struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+// NewCounters returns an empty Counters.
+func NewCounters() *Counters {
+	return &Counters{counts: make(map[string]int64)}
+}
+
+// Inc adds delta to name's count.
+func (c *Counters) Inc(name string, delta int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts[name] += delta
+}
+
+// Snapshot returns a copy of c's current counts.
+func (c *Counters) Snapshot() map[string]int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make(map[string]int64, len(c.counts))
+	for k, v := range c.counts {
+		out[k] = v
+	}
+	return out
+}
+
+// Merge adds every count in other into c.
+func (c *Counters) Merge(other map[string]int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, v := range other {
+		c.counts[k] += v
+	}
+}
+
+// taskCounters is the process-wide Counters that the currently
+// running map or reduce task's mapF/reduceF increments via Count.
+// mapF and reduceF are plain functions with a fixed signature (see
+// main/mrworker's plugin ABI), so they have no parameter of their own
+// to carry a *Counters through; Count reaches the current task's
+// counters through this package variable instead. A worker process
+// only ever runs one task at a time (see RunWorker), so resetting
+// taskCounters between tasks is enough to keep each task's report
+// accurate without any per-goroutine bookkeeping.
+var taskCounters = NewCounters()
+
+// Count increments name by delta in the counters of whatever map or
+// reduce task is currently running in this process. Call it from
+// mapF or reduceF to record events such as malformed records.
+// RunWorker reports each task's counts back to the coordinator once
+// the task finishes; Sequential accumulates every Count call across
+// the whole job instead, since it has no separate worker process to
+// report back to, and CountersSnapshot reads that job-wide total.
+func Count(name string, delta int64) {
+	taskCounters.Inc(name, delta)
+}
+
+// CountersSnapshot returns a copy of the current process's counters,
+// as accumulated by every Count call so far. Sequential resets
+// taskCounters at the start of each job, so callers can read a job's
+// counters with CountersSnapshot right after Sequential returns.
+// Coordinator.Counters is the equivalent for the RPC-driven path,
+// where mapF and reduceF run in separate worker processes and report
+// their counts back over RPC instead.
+func CountersSnapshot() map[string]int64 {
+	return taskCounters.Snapshot()
+}
@@ -0,0 +1,92 @@
+package mapreduce
+
+import (
+	"context"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestCountersIncSnapshotMerge(t *testing.T) {
+	c := NewCounters()
+	c.Inc("a", 2)
+	c.Inc("a", 3)
+	c.Inc("b", 1)
+
+	if got, want := c.Snapshot(), (map[string]int64{"a": 5, "b": 1}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Snapshot() = %v, want %v", got, want)
+	}
+
+	other := NewCounters()
+	other.Merge(map[string]int64{"a": 10, "c": 4})
+	if got, want := other.Snapshot(), (map[string]int64{"a": 10, "c": 4}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("after Merge, Snapshot() = %v, want %v", got, want)
+	}
+}
+
+// TestSequentialAggregatesCounters checks that Count calls from mapF
+// running across several concurrent map tasks all land in the same
+// job's counters, and that a later Sequential job in the same process
+// starts from zero instead of inheriting the previous job's counts.
+func TestSequentialAggregatesCounters(t *testing.T) {
+	withTempDir(t, func(dir string) {
+		if err := ioutil.WriteFile("in-0", []byte("a a b"), 0666); err != nil {
+			t.Fatalf("write input: %v", err)
+		}
+		if err := ioutil.WriteFile("in-1", []byte("c c c"), 0666); err != nil {
+			t.Fatalf("write input: %v", err)
+		}
+
+		countingMapF := func(filename, contents string) []KeyValue {
+			kvs := wordCountMapF(filename, contents)
+			Count("words_seen", int64(len(kvs)))
+			return kvs
+		}
+		reduceF := func(key string, values []string) string { return "" }
+
+		if err := Sequential(context.Background(), "job1", []string{"in-0", "in-1"}, 2, countingMapF, reduceF, MapOptions{}); err != nil {
+			t.Fatalf("Sequential: %v", err)
+		}
+		if got := CountersSnapshot()["words_seen"]; got != 6 {
+			t.Fatalf("job1 words_seen = %d, want 6", got)
+		}
+
+		if err := ioutil.WriteFile("in-2", []byte("x"), 0666); err != nil {
+			t.Fatalf("write input: %v", err)
+		}
+		if err := Sequential(context.Background(), "job2", []string{"in-2"}, 1, countingMapF, reduceF, MapOptions{}); err != nil {
+			t.Fatalf("Sequential: %v", err)
+		}
+		if got := CountersSnapshot()["words_seen"]; got != 1 {
+			t.Fatalf("job2 words_seen = %d, want 1 (job1's count must not leak in)", got)
+		}
+	})
+}
+
+// TestCoordinatorReportTaskAggregatesCounters checks that Counters
+// reported alongside successful tasks are merged into the
+// Coordinator's running totals, and that a task reported a second time
+// (e.g. a straggler whose result arrives after a reassignment already
+// succeeded) does not double its contribution.
+func TestCoordinatorReportTaskAggregatesCounters(t *testing.T) {
+	c := NewCoordinator(context.Background(), "job", []string{"in-0"}, 1, JobOptions{})
+
+	reply := GetTaskReply{}
+	if err := c.GetTask(&GetTaskArgs{}, &reply); err != nil {
+		t.Fatalf("GetTask: %v", err)
+	}
+
+	args := ReportTaskArgs{Type: MapTask, TaskNum: reply.TaskNum, Success: true, Counters: map[string]int64{"malformed_records": 3}}
+	if err := c.ReportTask(&args, &ReportTaskReply{}); err != nil {
+		t.Fatalf("ReportTask: %v", err)
+	}
+	// A straggler reporting the same already-Done task again must not
+	// double-count its counters.
+	if err := c.ReportTask(&args, &ReportTaskReply{}); err != nil {
+		t.Fatalf("ReportTask (duplicate): %v", err)
+	}
+
+	if got := c.Counters()["malformed_records"]; got != 3 {
+		t.Fatalf("malformed_records = %d, want 3 (duplicate report must not double-count)", got)
+	}
+}
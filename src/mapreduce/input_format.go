@@ -0,0 +1,106 @@
+package mapreduce
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// RecordReader yields successive records from an input file. Read
+// returns io.EOF (with an empty record) once the input is exhausted,
+// matching the convention bufio.Scanner and io.Reader already use in
+// this package.
+type RecordReader interface {
+	Read() (record string, err error)
+}
+
+// InputFormat controls how doMapStreaming splits an input file into
+// records before handing them to mapF. The zero value of MapOptions
+// uses LineInputFormat, which reproduces doMapStreaming's original
+// line-oriented behavior.
+type InputFormat interface {
+	NewRecordReader(r io.Reader) RecordReader
+}
+
+// LineInputFormat splits input on '\n', same as bufio.Scanner's
+// default ScanLines: each record is one line with the trailing
+// newline stripped.
+type LineInputFormat struct{}
+
+func (LineInputFormat) NewRecordReader(r io.Reader) RecordReader {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	return &scannerRecordReader{s}
+}
+
+// DelimitedInputFormat splits input on an arbitrary byte delimiter
+// instead of always '\n', for record formats such as NUL-separated or
+// comma-separated streams.
+type DelimitedInputFormat struct{ Delim byte }
+
+func (f DelimitedInputFormat) NewRecordReader(r io.Reader) RecordReader {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	s.Split(splitOn(f.Delim))
+	return &scannerRecordReader{s}
+}
+
+// splitOn returns a bufio.SplitFunc that behaves like bufio.ScanLines
+// but for an arbitrary delimiter byte.
+func splitOn(delim byte) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+		if atEOF && len(data) == 0 {
+			return 0, nil, nil
+		}
+		if i := bytes.IndexByte(data, delim); i >= 0 {
+			return i + 1, data[:i], nil
+		}
+		if atEOF {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	}
+}
+
+// scannerRecordReader adapts a *bufio.Scanner to RecordReader.
+type scannerRecordReader struct{ s *bufio.Scanner }
+
+func (r *scannerRecordReader) Read() (string, error) {
+	if !r.s.Scan() {
+		if err := r.s.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return r.s.Text(), nil
+}
+
+// FixedLengthInputFormat splits input into fixed-size byte records,
+// for formats with no record separator at all (e.g. fixed-width
+// binary logs). The final record is short if the input length is not
+// a multiple of Size.
+type FixedLengthInputFormat struct{ Size int }
+
+func (f FixedLengthInputFormat) NewRecordReader(r io.Reader) RecordReader {
+	return &fixedLengthRecordReader{r: bufio.NewReader(r), size: f.Size}
+}
+
+type fixedLengthRecordReader struct {
+	r    *bufio.Reader
+	size int
+}
+
+func (r *fixedLengthRecordReader) Read() (string, error) {
+	buf := make([]byte, r.size)
+	n, err := io.ReadFull(r.r, buf)
+	if n == 0 {
+		if err == io.ErrUnexpectedEOF {
+			return "", io.EOF
+		}
+		return "", err
+	}
+	if err == io.ErrUnexpectedEOF || err == io.EOF {
+		return string(buf[:n]), nil // short final record; next Read reports io.EOF
+	}
+	return string(buf[:n]), err
+}
@@ -0,0 +1,73 @@
+package mapreduce
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func readAllRecords(t *testing.T, f InputFormat, input string) []string {
+	t.Helper()
+	r := f.NewRecordReader(strings.NewReader(input))
+	var records []string
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestLineInputFormat(t *testing.T) {
+	got := readAllRecords(t, LineInputFormat{}, "the\nquick\nbrown\n")
+	want := []string{"the", "quick", "brown"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestDelimitedInputFormat(t *testing.T) {
+	got := readAllRecords(t, DelimitedInputFormat{Delim: ','}, "the,quick,brown")
+	want := []string{"the", "quick", "brown"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestFixedLengthInputFormat(t *testing.T) {
+	got := readAllRecords(t, FixedLengthInputFormat{Size: 3}, "aaabbbc")
+	want := []string{"aaa", "bbb", "c"} // final record short since 7 is not a multiple of 3
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+// TestDoMapStreamingUsesCustomInputFormat checks that doMapStreaming
+// splits records using opts.InputFormat instead of always assuming
+// line-oriented input.
+func TestDoMapStreamingUsesCustomInputFormat(t *testing.T) {
+	const jobName = "test"
+	const nReduce = 1
+	input := "the,quick,brown,fox"
+
+	withTempDir(t, func(dir string) {
+		if err := ioutil.WriteFile("in-0", []byte(input), 0666); err != nil {
+			t.Fatalf("write input: %v", err)
+		}
+		opts := MapOptions{Streaming: true, InputFormat: DelimitedInputFormat{Delim: ','}}
+		if err := doMap(context.Background(), jobName, 0, Split{File: "in-0"}, nReduce, wordCountMapF, opts); err != nil {
+			t.Fatalf("doMap: %v", err)
+		}
+		kvs := readPartition(t, JSONCodec{}, jobName, 0, 0)
+		if len(kvs) != 4 {
+			t.Fatalf("got %d KVs, want 4 (one per comma-separated word)", len(kvs))
+		}
+	})
+}
@@ -0,0 +1,87 @@
+package mapreduce
+
+import "context"
+
+type jobPhase string
+
+const (
+	mapPhase    jobPhase = "mapPhase"
+	reducePhase jobPhase = "reducePhase"
+)
+
+// Master drives a MapReduce job sequentially, running every map and
+// reduce task in the calling process instead of farming tasks out to
+// remote workers. Coordinator (see coordinator.go) is the
+// fault-tolerant, RPC-driven counterpart for running across a pool of
+// worker processes.
+type Master struct {
+	jobName string
+	splits  []Split
+	nReduce int
+	mapF    func(filename string, contents string) []KeyValue
+	reduceF func(key string, values []string) string
+	opts    MapOptions
+}
+
+// Sequential runs a complete MapReduce job in-process: every map
+// task, then every reduce task, using mapF and reduceF supplied by the
+// caller. opts carries the map phase's codec, partitioner/combiner
+// hooks, and streaming toggle (see doMap and MapOptions); the zero
+// value reproduces doMap's original behavior. opts.SplitSize, if set,
+// divides any file bigger than it into several map tasks instead of
+// exactly one. The reduce phase always uses opts.Codec to read the
+// intermediate files the map phase wrote.
+//
+// Sequential returns the first error any map or reduce task reported,
+// including a failure to stat files while planning splits. It does
+// not start the reduce phase if the map phase failed, since reduce
+// tasks would otherwise read incomplete intermediate files. Cancelling
+// ctx, or its deadline passing, stops the job in the same way:
+// schedule returns ctx.Err() and Sequential propagates it without
+// starting the reduce phase.
+//
+// Sequential resets the process's Counters before starting, so a
+// caller can read this job's counts with CountersSnapshot once
+// Sequential returns without earlier jobs' counts leaking in.
+//
+// When opts.SaltKey is set, Sequential also runs a framework-managed
+// finalization step after the reduce phase (see finalizeSalting) that
+// re-aggregates every hot key's salted, spread-out partial results
+// back into one final value, so a caller reading mergeName's output
+// files afterward can't tell the job was salted at all.
+func Sequential(
+	ctx context.Context,
+	jobName string,
+	files []string,
+	nReduce int,
+	mapF func(filename string, contents string) []KeyValue,
+	reduceF func(key string, values []string) string,
+	opts MapOptions,
+) error {
+	taskCounters = NewCounters()
+
+	opts = opts.withDefaults()
+	splits, err := planSplits(files, opts.SplitSize)
+	if err != nil {
+		return err
+	}
+
+	mr := &Master{
+		jobName: jobName,
+		splits:  splits,
+		nReduce: nReduce,
+		mapF:    mapF,
+		reduceF: reduceF,
+		opts:    opts,
+	}
+	if err := mr.schedule(ctx, mapPhase); err != nil {
+		return err
+	}
+	if err := mr.schedule(ctx, reducePhase); err != nil {
+		return err
+	}
+	if opts.SaltKey != nil && opts.SaltBuckets > 1 {
+		return finalizeSalting(ctx, mr)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package mapreduce
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestSequentialNReduceExceedsDistinctKeys runs a full Sequential job
+// with far more reduce partitions than there are distinct keys, so
+// most (mapTask, reduceTask) pairs never get an intermediate file.
+// doReduce must treat those as "zero KVs from that map task" instead
+// of panicking.
+func TestSequentialNReduceExceedsDistinctKeys(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mr-sequential-test")
+	if err != nil {
+		t.Fatalf("create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+	defer os.Chdir(wd)
+
+	inFile := filepath.Join(dir, "in-0")
+	if err := ioutil.WriteFile(inFile, []byte("a a b"), 0666); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+
+	mapF := func(filename string, contents string) []KeyValue {
+		var kvs []KeyValue
+		for _, word := range strings.Fields(contents) {
+			kvs = append(kvs, KeyValue{word, "1"})
+		}
+		return kvs
+	}
+	reduceF := func(key string, values []string) string {
+		return strconv.Itoa(len(values))
+	}
+
+	const jobName = "test"
+	const nReduce = 10 // far more partitions than the 2 distinct keys above
+
+	if err := Sequential(context.Background(), jobName, []string{inFile}, nReduce, mapF, reduceF, MapOptions{}); err != nil {
+		t.Fatalf("Sequential: %v", err)
+	}
+
+	counts := make(map[string]string)
+	for r := 0; r < nReduce; r++ {
+		outFd, err := os.Open(mergeName(jobName, r))
+		if err != nil {
+			t.Fatalf("open reduce output %d: %v", r, err)
+		}
+		dec := json.NewDecoder(outFd)
+		for {
+			var kv KeyValue
+			if err := dec.Decode(&kv); err != nil {
+				break
+			}
+			counts[kv.Key] = kv.Value
+		}
+		outFd.Close()
+	}
+
+	if counts["a"] != "2" || counts["b"] != "1" {
+		t.Fatalf("got counts %v, want a=2 b=1", counts)
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !linux && !darwin
+
+package mapreduce
+
+import (
+	"fmt"
+	"os"
+)
+
+// mmapFile is the fallback for platforms this package has no mmap
+// syscall wiring for. It fails loudly instead of silently falling back
+// to a regular read, so a caller who opted into MapOptions.MMapInput
+// finds out immediately that this build can't honor it, rather than
+// getting no error and no speedup.
+func mmapFile(f *os.File) (data []byte, unmap func() error, err error) {
+	return nil, nil, fmt.Errorf("mmap input is not supported on this platform")
+}
@@ -0,0 +1,80 @@
+package mapreduce
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// TestDoMapMMapInputMatchesReadAll checks that MMapInput produces the
+// same map output as the default ioutil.ReadAll path for both plain
+// and gzip-compressed input.
+func TestDoMapMMapInputMatchesReadAll(t *testing.T) {
+	const jobName = "test"
+	const nReduce = 2
+
+	run := func(inFile string, write func(name string)) []KeyValue {
+		var got []KeyValue
+		withTempDir(t, func(dir string) {
+			write(inFile)
+			opts := MapOptions{MMapInput: true}
+			if err := doMap(context.Background(), jobName, 0, Split{File: inFile}, nReduce, wordCountMapF, opts); err != nil {
+				t.Fatalf("doMap with MMapInput: %v", err)
+			}
+			for r := 0; r < nReduce; r++ {
+				got = append(got, readPartition(t, JSONCodec{}, jobName, 0, r)...)
+			}
+		})
+		sort.Slice(got, func(i, j int) bool { return got[i].Key < got[j].Key })
+		return got
+	}
+
+	plain := run("in-0", func(name string) {
+		if err := ioutil.WriteFile(name, []byte("the quick brown fox"), 0666); err != nil {
+			t.Fatalf("write input: %v", err)
+		}
+	})
+	want := []KeyValue{{Key: "brown", Value: "1"}, {Key: "fox", Value: "1"}, {Key: "quick", Value: "1"}, {Key: "the", Value: "1"}}
+	if !reflect.DeepEqual(plain, want) {
+		t.Fatalf("mmapped plain input: got %v, want %v", plain, want)
+	}
+
+	compressed := run("in-0.gz", func(name string) {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		if _, err := gz.Write([]byte("the quick brown fox")); err != nil {
+			t.Fatalf("gzip write: %v", err)
+		}
+		if err := gz.Close(); err != nil {
+			t.Fatalf("gzip close: %v", err)
+		}
+		if err := ioutil.WriteFile(name, buf.Bytes(), 0666); err != nil {
+			t.Fatalf("write input: %v", err)
+		}
+	})
+	if !reflect.DeepEqual(compressed, want) {
+		t.Fatalf("mmapped gzip input: got %v, want %v", compressed, want)
+	}
+}
+
+// TestDoMapMMapInputEmptyFile checks that an empty input file (which
+// mmapFile handles specially, since syscall.Mmap rejects a
+// zero-length mapping) doesn't error and simply produces no output.
+func TestDoMapMMapInputEmptyFile(t *testing.T) {
+	const jobName = "test"
+	const nReduce = 1
+
+	withTempDir(t, func(dir string) {
+		if err := ioutil.WriteFile("in-0", []byte(""), 0666); err != nil {
+			t.Fatalf("write input: %v", err)
+		}
+		opts := MapOptions{MMapInput: true}
+		if err := doMap(context.Background(), jobName, 0, Split{File: "in-0"}, nReduce, wordCountMapF, opts); err != nil {
+			t.Fatalf("doMap with MMapInput on an empty file: %v", err)
+		}
+	})
+}
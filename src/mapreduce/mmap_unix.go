@@ -0,0 +1,31 @@
+//go:build linux || darwin
+
+package mapreduce
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// mmapFile memory-maps the whole of f, which must already be open for
+// reading, and returns its contents as a byte slice backed directly by
+// the OS page cache instead of a copy in Go's heap, plus a function
+// the caller must call to unmap it once done (and before f is closed).
+// An empty file maps to a nil slice and a no-op unmap, since
+// syscall.Mmap rejects a zero-length mapping.
+func mmapFile(f *os.File) (data []byte, unmap func() error, err error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, fmt.Errorf("stat file:%s cause error:%w", f.Name(), err)
+	}
+	if info.Size() == 0 {
+		return nil, func() error { return nil }, nil
+	}
+
+	data, err = syscall.Mmap(int(f.Fd()), 0, int(info.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, fmt.Errorf("mmap file:%s cause error:%w", f.Name(), err)
+	}
+	return data, func() error { return syscall.Munmap(data) }, nil
+}
@@ -0,0 +1,71 @@
+package mapreduce
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// TestDoMapEncodeParallelismDoesNotAffectOutput checks that
+// EncodeParallelism only changes how many partition files doMap
+// encodes concurrently, not what ends up in them.
+func TestDoMapEncodeParallelismDoesNotAffectOutput(t *testing.T) {
+	const jobName = "test"
+	const nReduce = 8
+	input := "the quick brown fox jumps over the lazy dog and then the fox runs away"
+
+	read := func(parallelism int) map[int][]KeyValue {
+		out := map[int][]KeyValue{}
+		withTempDir(t, func(dir string) {
+			if err := ioutil.WriteFile("in-0", []byte(input), 0666); err != nil {
+				t.Fatalf("write input: %v", err)
+			}
+			opts := MapOptions{EncodeParallelism: parallelism}
+			if err := doMap(context.Background(), jobName, 0, Split{File: "in-0"}, nReduce, wordCountMapF, opts); err != nil {
+				t.Fatalf("doMap: %v", err)
+			}
+			for r := 0; r < nReduce; r++ {
+				if _, err := os.Stat(reduceName(jobName, 0, r)); err != nil {
+					continue
+				}
+				out[r] = readPartition(t, JSONCodec{}, jobName, 0, r)
+			}
+		})
+		return out
+	}
+
+	serial := read(0)
+	parallel := read(4)
+
+	for r := 0; r < nReduce; r++ {
+		sort.Slice(serial[r], func(i, j int) bool { return serial[r][i].Key < serial[r][j].Key })
+		sort.Slice(parallel[r], func(i, j int) bool { return parallel[r][i].Key < parallel[r][j].Key })
+		if !reflect.DeepEqual(serial[r], parallel[r]) {
+			t.Fatalf("partition %d differs between serial and parallel encoding: %v vs %v", r, serial[r], parallel[r])
+		}
+	}
+}
+
+// TestDoMapEncodeParallelismPropagatesError checks that an error from
+// one partition's write still fails the whole map task even when
+// EncodeParallelism lets several partitions encode at once.
+func TestDoMapEncodeParallelismPropagatesError(t *testing.T) {
+	const jobName = "test"
+	const nReduce = 4
+
+	withTempDir(t, func(dir string) {
+		if err := ioutil.WriteFile("in-0", []byte("a b c d e f g h"), 0666); err != nil {
+			t.Fatalf("write input: %v", err)
+		}
+
+		budget := 1
+		opts := MapOptions{EncodeParallelism: 4, Codec: failAfterNCodec{n: &budget}}
+		err := doMap(context.Background(), jobName, 0, Split{File: "in-0"}, nReduce, wordCountMapF, opts)
+		if err == nil {
+			t.Fatal("doMap returned nil error despite a failing codec")
+		}
+	})
+}
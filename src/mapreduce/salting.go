@@ -0,0 +1,167 @@
+package mapreduce
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// saltSeparator joins a hot key to its salt suffix. It is a control
+// byte legitimate keys are vanishingly unlikely to contain, the same
+// tradeoff this package already makes elsewhere by treating keys as
+// opaque strings rather than escaping them.
+const saltSeparator = "\x00"
+
+// saltKeyValues is doMap's hook for spreading a hot key across several
+// destination partitions instead of funneling every one of its values
+// through the single reduce task PartitionF would otherwise send them
+// all to. hot reports whether a key is hot enough to salt; buckets is
+// how many salted variants a hot key is spread across. Occurrences of
+// the same hot key are salted round-robin, not by hashing the value,
+// so a key's values divide evenly across its buckets instead of
+// clumping into one. Cold keys (hot returns false), and every key when
+// hot is nil or buckets <= 1, pass through unchanged.
+//
+// Salting only pays off if reduceF can correctly aggregate its own
+// past output, the same requirement CombineF already places on it:
+// finalizeSalting calls reduceF a second time on a hot key's per-bucket
+// partial results to recombine its true final value, so reduceF must
+// be associative and commutative over its own output, not just over
+// raw map values.
+func saltKeyValues(kvs []KeyValue, hot func(key string) bool, buckets int) []KeyValue {
+	if hot == nil || buckets <= 1 {
+		return kvs
+	}
+
+	next := make(map[string]int)
+	out := make([]KeyValue, len(kvs))
+	for i, kv := range kvs {
+		if !hot(kv.Key) {
+			out[i] = kv
+			continue
+		}
+		bucket := next[kv.Key]
+		next[kv.Key] = (bucket + 1) % buckets
+		out[i] = KeyValue{Key: saltKey(kv.Key, bucket), Value: kv.Value}
+	}
+	return out
+}
+
+// saltKey appends bucket as a salt suffix to key.
+func saltKey(key string, bucket int) string {
+	return key + saltSeparator + strconv.Itoa(bucket)
+}
+
+// unsaltKey strips a salt suffix saltKeyValues may have appended,
+// returning key unchanged if it never had one. finalizeSalting uses it
+// to regroup a hot key's per-bucket reduce results back under their
+// original key.
+func unsaltKey(key string) string {
+	i := strings.LastIndex(key, saltSeparator)
+	if i < 0 {
+		return key
+	}
+	return key[:i]
+}
+
+// finalizeSalting re-aggregates a salted Sequential job's reduce-phase
+// output: it reads every reduce task's final output file, regroups
+// entries by their pre-salt key (see unsaltKey), calls mr.reduceF once
+// more per group to combine a hot key's per-bucket partial results
+// into its true final value, and rewrites the output files so that,
+// from the caller's perspective, salting behaves exactly like an
+// unsalted job -- mergeName(mr.jobName, r) for r in [0, mr.nReduce)
+// still holds the job's complete final output afterward, just
+// redistributed across the same files by running mr.opts.PartitionF
+// over each key's now-unsalted form.
+func finalizeSalting(ctx context.Context, mr *Master) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	grouped := make(map[string][]string)
+	for r := 0; r < mr.nReduce; r++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := readMergedInto(grouped, mergeName(mr.jobName, r)); err != nil {
+			return err
+		}
+	}
+
+	keys := make([]string, 0, len(grouped))
+	for key := range grouped {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	final := make(map[string]string, len(keys))
+	byPartition := make(map[int][]string)
+	for _, key := range keys {
+		final[key] = mr.reduceF(key, grouped[key])
+		r := mr.opts.PartitionF(key, mr.nReduce)
+		byPartition[r] = append(byPartition[r], key)
+	}
+
+	for r := 0; r < mr.nReduce; r++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rkeys := byPartition[r]
+		sort.Strings(rkeys)
+		if err := writeMergedFile(mergeName(mr.jobName, r), rkeys, final); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readMergedInto decodes one reduce task's final output file (always
+// JSON regardless of the job's intermediate-file codec, see doReduce)
+// and appends each entry's value to kvMap under its pre-salt key, so a
+// hot key's per-bucket partial results end up grouped together no
+// matter which reduce task produced each one.
+func readMergedInto(kvMap map[string][]string, fileName string) error {
+	f, err := os.Open(fileName)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("open file:%s cause error:%w", fileName, err)
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	for {
+		var kv KeyValue
+		if err := dec.Decode(&kv); err != nil {
+			break
+		}
+		key := unsaltKey(kv.Key)
+		kvMap[key] = append(kvMap[key], kv.Value)
+	}
+	return nil
+}
+
+// writeMergedFile writes fileName the same way doReduce does: one
+// JSON-encoded KeyValue per key in keys, using final for each key's
+// value.
+func writeMergedFile(fileName string, keys []string, final map[string]string) error {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return fmt.Errorf("create file:%s cause error:%w", fileName, err)
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	for _, key := range keys {
+		if err := enc.Encode(KeyValue{key, final[key]}); err != nil {
+			return fmt.Errorf("encode error:%w", err)
+		}
+	}
+	return nil
+}
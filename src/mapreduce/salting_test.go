@@ -0,0 +1,184 @@
+package mapreduce
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"testing"
+)
+
+// decodeMergedFile reads every KeyValue out of a reduce task's final
+// output file, the same format writeMergedFile/doReduce both write.
+func decodeMergedFile(t *testing.T, fileName string) []KeyValue {
+	t.Helper()
+
+	f, err := os.Open(fileName)
+	if err != nil {
+		t.Fatalf("open merged file: %v", err)
+	}
+	defer f.Close()
+
+	var kvs []KeyValue
+	dec := json.NewDecoder(f)
+	for {
+		var kv KeyValue
+		if err := dec.Decode(&kv); err != nil {
+			break
+		}
+		kvs = append(kvs, kv)
+	}
+	return kvs
+}
+
+// TestSaltKeyValuesSpreadsHotKeyRoundRobin checks that repeated
+// occurrences of a hot key are salted round-robin across buckets,
+// while a cold key passes through unchanged.
+func TestSaltKeyValuesSpreadsHotKeyRoundRobin(t *testing.T) {
+	kvs := []KeyValue{
+		{Key: "hot", Value: "1"},
+		{Key: "cold", Value: "1"},
+		{Key: "hot", Value: "1"},
+		{Key: "hot", Value: "1"},
+	}
+	hot := func(key string) bool { return key == "hot" }
+
+	got := saltKeyValues(kvs, hot, 2)
+
+	if got[1].Key != "cold" {
+		t.Fatalf("cold key was salted: %v", got[1])
+	}
+	if got[0].Key == got[2].Key {
+		t.Fatalf("two of three hot occurrences landed in the same bucket: %v", got)
+	}
+	if unsaltKey(got[0].Key) != "hot" || unsaltKey(got[2].Key) != "hot" {
+		t.Fatalf("salted keys don't unsalt back to \"hot\": %v", got)
+	}
+	// Round-robin over 2 buckets: occurrence 3 should land back in
+	// occurrence 1's bucket.
+	if got[0].Key != got[3].Key {
+		t.Fatalf("third hot occurrence did not wrap back to the first bucket: %v", got)
+	}
+}
+
+// TestSaltKeyValuesDisabled checks that a nil hot predicate or
+// buckets <= 1 leaves kvs untouched.
+func TestSaltKeyValuesDisabled(t *testing.T) {
+	kvs := []KeyValue{{Key: "hot", Value: "1"}}
+	hot := func(key string) bool { return true }
+
+	if got := saltKeyValues(kvs, nil, 4); got[0].Key != "hot" {
+		t.Fatalf("nil hot predicate: got key %q, want unsalted", got[0].Key)
+	}
+	if got := saltKeyValues(kvs, hot, 1); got[0].Key != "hot" {
+		t.Fatalf("buckets <= 1: got key %q, want unsalted", got[0].Key)
+	}
+}
+
+// TestDoMapSaltsHotKeyAcrossPartitions checks that doMap, given a hot
+// predicate and enough reduce tasks, actually spreads a hot key's
+// intermediate output across more than one reduceName file instead of
+// funneling it all through whichever single partition ihash picks.
+func TestDoMapSaltsHotKeyAcrossPartitions(t *testing.T) {
+	const jobName = "test"
+	const nReduce = 8
+
+	withTempDir(t, func(dir string) {
+		if err := ioutil.WriteFile("in-0", []byte(""), 0666); err != nil {
+			t.Fatalf("write input: %v", err)
+		}
+
+		hotMapF := func(filename, contents string) []KeyValue {
+			kvs := make([]KeyValue, 20)
+			for i := range kvs {
+				kvs[i] = KeyValue{Key: "", Value: strconv.Itoa(i)}
+			}
+			return kvs
+		}
+		// The default ihash-based routing, spelled out: each salted
+		// variant of "" hashes on its own, so the variants can land in
+		// different partitions.
+		partitionF := func(key string, nReduce int) int { return ihash(key) % nReduce }
+
+		opts := MapOptions{
+			SaltKey:     func(key string) bool { return key == "" },
+			SaltBuckets: 4,
+			PartitionF:  partitionF,
+		}
+		if err := doMap(context.Background(), jobName, 0, Split{File: "in-0"}, nReduce, hotMapF, opts); err != nil {
+			t.Fatalf("doMap: %v", err)
+		}
+
+		partitionsWithOutput := 0
+		for r := 0; r < nReduce; r++ {
+			if _, err := os.Stat(reduceName(jobName, 0, r)); err == nil {
+				partitionsWithOutput++
+			}
+		}
+		if partitionsWithOutput < 2 {
+			t.Fatalf("hot key's output landed in %d partition(s), want salting to spread it across more than one", partitionsWithOutput)
+		}
+	})
+}
+
+// TestSequentialFinalizesSaltedJob runs a full Sequential job with a
+// single hot key that, unsalted, would force every value through one
+// reduce task, and checks that finalizeSalting still produces the
+// single correct final answer for it: reduceF here sums the numeric
+// values it's given, which works whether it's summing raw map values
+// or, in the finalization pass, summing a hot key's per-bucket partial
+// sums, since summation is associative.
+func TestSequentialFinalizesSaltedJob(t *testing.T) {
+	const jobName = "test"
+	const nReduce = 4
+
+	withTempDir(t, func(dir string) {
+		if err := ioutil.WriteFile("in-0", []byte(""), 0666); err != nil {
+			t.Fatalf("write input: %v", err)
+		}
+
+		countMapF := func(filename, contents string) []KeyValue {
+			kvs := make([]KeyValue, 40)
+			for i := range kvs {
+				kvs[i] = KeyValue{Key: "hot", Value: "1"}
+			}
+			return kvs
+		}
+		sumReduceF := func(key string, values []string) string {
+			total := 0
+			for _, v := range values {
+				n, err := strconv.Atoi(v)
+				if err != nil {
+					t.Fatalf("sumReduceF got non-numeric value %q", v)
+				}
+				total += n
+			}
+			return strconv.Itoa(total)
+		}
+
+		opts := MapOptions{
+			SaltKey:     func(key string) bool { return key == "hot" },
+			SaltBuckets: 4,
+		}
+		if err := Sequential(context.Background(), jobName, []string{"in-0"}, nReduce, countMapF, sumReduceF, opts); err != nil {
+			t.Fatalf("Sequential: %v", err)
+		}
+
+		found := false
+		for r := 0; r < nReduce; r++ {
+			for _, kv := range decodeMergedFile(t, mergeName(jobName, r)) {
+				if kv.Key != "hot" {
+					t.Fatalf("finalized output has a leftover salted key: %q", kv.Key)
+				}
+				if kv.Value != "40" {
+					t.Fatalf("finalized value for \"hot\" = %q, want \"40\"", kv.Value)
+				}
+				found = true
+			}
+		}
+		if !found {
+			t.Fatal("never found \"hot\" in any merged output file")
+		}
+	})
+}
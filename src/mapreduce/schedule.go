@@ -0,0 +1,54 @@
+package mapreduce
+
+import (
+	"context"
+	"sync"
+)
+
+// schedule runs every task of the given phase to completion, one
+// goroutine per task, and waits for them all to finish before
+// returning. It returns the first error any task reported, if any;
+// the other tasks are still allowed to finish rather than being
+// aborted mid-flight. Cancelling ctx (or letting its deadline pass)
+// stops any task that has not yet started and, via doMap/doReduce's
+// own ctx checks, tasks already running.
+func (mr *Master) schedule(ctx context.Context, phase jobPhase) error {
+	var nTasks int
+	var nOther int
+
+	switch phase {
+	case mapPhase:
+		nTasks = len(mr.splits)
+		nOther = mr.nReduce
+	case reducePhase:
+		nTasks = mr.nReduce
+		nOther = len(mr.splits)
+	}
+
+	errs := make([]error, nTasks)
+	var wg sync.WaitGroup
+	for i := 0; i < nTasks; i++ {
+		wg.Add(1)
+		go func(taskNum int) {
+			defer wg.Done()
+			if err := ctx.Err(); err != nil {
+				errs[taskNum] = err
+				return
+			}
+			switch phase {
+			case mapPhase:
+				errs[taskNum] = doMap(ctx, mr.jobName, taskNum, mr.splits[taskNum], nOther, mr.mapF, mr.opts)
+			case reducePhase:
+				errs[taskNum] = doReduce(ctx, mr.jobName, taskNum, mergeName(mr.jobName, taskNum), nOther, mr.reduceF, mr.opts.Codec, mr.opts.SecondarySort)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
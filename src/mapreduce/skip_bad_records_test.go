@@ -0,0 +1,72 @@
+package mapreduce
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+)
+
+// TestDoMapStreamingSkipsBadRecords checks that a mapF panicking on
+// one record is recovered and skipped, with every other record still
+// processed and written out normally, and that CountersSnapshot
+// reflects the skip.
+func TestDoMapStreamingSkipsBadRecords(t *testing.T) {
+	const jobName = "test"
+	const nReduce = 1
+	input := "good\nBAD\ngood\ngood"
+
+	panickyMapF := func(filename, record string) []KeyValue {
+		if record == "BAD" {
+			panic("simulated bad record")
+		}
+		return []KeyValue{{Key: record, Value: "1"}}
+	}
+
+	withTempDir(t, func(dir string) {
+		taskCounters = NewCounters()
+		if err := ioutil.WriteFile("in-0", []byte(input), 0666); err != nil {
+			t.Fatalf("write input: %v", err)
+		}
+
+		opts := MapOptions{Streaming: true, SkipBadRecords: true}
+		if err := doMap(context.Background(), jobName, 0, Split{File: "in-0"}, nReduce, panickyMapF, opts); err != nil {
+			t.Fatalf("doMap: %v", err)
+		}
+
+		kvs := readPartition(t, JSONCodec{}, jobName, 0, 0)
+		if len(kvs) != 3 {
+			t.Fatalf("got %d KVs, want 3 (every non-BAD record)", len(kvs))
+		}
+		if got := CountersSnapshot()["skipped_records"]; got != 1 {
+			t.Fatalf("skipped_records = %d, want 1", got)
+		}
+	})
+}
+
+// TestDoMapStreamingSkipBadRecordsExceedsMaxRatio checks that once the
+// fraction of skipped records exceeds opts.MaxSkipRatio, the task
+// fails instead of continuing to silently discard records.
+func TestDoMapStreamingSkipBadRecordsExceedsMaxRatio(t *testing.T) {
+	const jobName = "test"
+	const nReduce = 1
+	input := "BAD\nBAD\ngood"
+
+	panickyMapF := func(filename, record string) []KeyValue {
+		if record == "BAD" {
+			panic("simulated bad record")
+		}
+		return []KeyValue{{Key: record, Value: "1"}}
+	}
+
+	withTempDir(t, func(dir string) {
+		if err := ioutil.WriteFile("in-0", []byte(input), 0666); err != nil {
+			t.Fatalf("write input: %v", err)
+		}
+
+		opts := MapOptions{Streaming: true, SkipBadRecords: true, MaxSkipRatio: 0.5}
+		err := doMap(context.Background(), jobName, 0, Split{File: "in-0"}, nReduce, panickyMapF, opts)
+		if err == nil {
+			t.Fatal("doMap returned nil error, want one reporting the exceeded skip ratio")
+		}
+	})
+}
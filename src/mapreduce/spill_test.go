@@ -0,0 +1,82 @@
+package mapreduce
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// TestDoMapSpillMatchesUnspilled checks that spilling partition
+// buffers to disk in small slices (opts.SpillSize) produces the same
+// partitioned output as buffering every partition in full.
+func TestDoMapSpillMatchesUnspilled(t *testing.T) {
+	const jobName = "test"
+	const nReduce = 3
+	input := "the quick brown\nfox jumps over\nthe lazy dog\nthe fox runs"
+
+	unspilled := map[int][]KeyValue{}
+	withTempDir(t, func(dir string) {
+		if err := ioutil.WriteFile("in-0", []byte(input), 0666); err != nil {
+			t.Fatalf("write input: %v", err)
+		}
+		if err := doMap(context.Background(), jobName, 0, Split{File: "in-0"}, nReduce, wordCountMapF, MapOptions{}); err != nil {
+			t.Fatalf("doMap: %v", err)
+		}
+		for r := 0; r < nReduce; r++ {
+			unspilled[r] = readPartition(t, JSONCodec{}, jobName, 0, r)
+		}
+	})
+
+	spilled := map[int][]KeyValue{}
+	withTempDir(t, func(dir string) {
+		if err := ioutil.WriteFile("in-0", []byte(input), 0666); err != nil {
+			t.Fatalf("write input: %v", err)
+		}
+		// A tiny SpillSize forces several spills for this small input.
+		if err := doMap(context.Background(), jobName, 0, Split{File: "in-0"}, nReduce, wordCountMapF, MapOptions{SpillSize: 8}); err != nil {
+			t.Fatalf("doMap: %v", err)
+		}
+		for r := 0; r < nReduce; r++ {
+			spilled[r] = readPartition(t, JSONCodec{}, jobName, 0, r)
+		}
+	})
+
+	for r := 0; r < nReduce; r++ {
+		a, b := unspilled[r], spilled[r]
+		sort.Slice(a, func(i, j int) bool { return a[i].Key < a[j].Key })
+		sort.Slice(b, func(i, j int) bool { return b[i].Key < b[j].Key })
+		if !reflect.DeepEqual(a, b) {
+			t.Fatalf("partition %d: unspilled %v != spilled %v", r, a, b)
+		}
+	}
+}
+
+// TestDoMapSpillDiscardsPartitionsOnError checks that spillMap leaves
+// no intermediate files behind at all when a spill partway through
+// fails, matching doMapStreaming's all-or-nothing guarantee.
+func TestDoMapSpillDiscardsPartitionsOnError(t *testing.T) {
+	const jobName = "test"
+	const nReduce = 2
+	input := "aaa\nzzz\n"
+
+	withTempDir(t, func(dir string) {
+		if err := ioutil.WriteFile("in-0", []byte(input), 0666); err != nil {
+			t.Fatalf("write input: %v", err)
+		}
+
+		budget := 1
+		opts := MapOptions{SpillSize: 1, Codec: failAfterNCodec{n: &budget}}
+		if err := doMap(context.Background(), jobName, 0, Split{File: "in-0"}, nReduce, wordCountMapF, opts); err == nil {
+			t.Fatal("doMap returned nil error, want the simulated encode failure")
+		}
+
+		for r := 0; r < nReduce; r++ {
+			if _, err := os.Stat(reduceName(jobName, 0, r)); !os.IsNotExist(err) {
+				t.Fatalf("partition %d file exists after a failed map task, want none: err=%v", r, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,178 @@
+package mapreduce
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Split describes one map task's share of an input file: the byte
+// range [Start, End) it should read. End == 0 is a sentinel meaning
+// "to EOF", so the zero Split for a file (Start: 0, End: 0) reproduces
+// doMap's original one-file-per-task behavior without ever stat-ing
+// the file.
+type Split struct {
+	File  string
+	Start int64
+	End   int64
+}
+
+// planSplits turns files into one Split per map task. splitSize <= 0
+// disables splitting: every file becomes exactly one unbounded Split,
+// matching doMap's original behavior and requiring no filesystem
+// access. splitSize > 0 divides any file bigger than splitSize into
+// several byte-range Splits so a single large file gets more than one
+// map task instead of no parallelism at all; splitRecordReader aligns
+// each range on a record boundary when it actually reads the file.
+func planSplits(files []string, splitSize int64) ([]Split, error) {
+	if splitSize <= 0 {
+		splits := make([]Split, len(files))
+		for i, f := range files {
+			splits[i] = Split{File: f}
+		}
+		return splits, nil
+	}
+
+	var splits []Split
+	for _, f := range files {
+		info, err := os.Stat(f)
+		if err != nil {
+			return nil, fmt.Errorf("stat file:%s cause error:%w", f, err)
+		}
+		size := info.Size()
+		if size == 0 || !splittable(f) {
+			splits = append(splits, Split{File: f})
+			continue
+		}
+		for start := int64(0); start < size; start += splitSize {
+			end := start + splitSize
+			if end > size {
+				end = size
+			}
+			splits = append(splits, Split{File: f, Start: start, End: end})
+		}
+	}
+	return splits, nil
+}
+
+// splitRecordReader wraps another RecordReader so it yields only the
+// records belonging to one Split: if the split does not start at
+// offset 0, it first discards the partial record Start lands in the
+// middle of (the previous split's reader already reads through to the
+// end of that same record), and it stops once the records it has read
+// run past End-Start bytes, so it also yields the record that crosses
+// End or starts exactly at End. A split whose discarded partial record
+// already runs past End yields nothing. Together these rules give
+// every record in the file to exactly one split, regardless of where the byte range was
+// cut.
+//
+// consumed is tracked from the length of the records Read returns,
+// not from bytes read off the underlying file: the RecordReader's own
+// buffering (e.g. bufio.Scanner filling its buffer in one big Read)
+// means bytes-off-the-file and bytes-yielded-so-far can diverge
+// wildly, and only the latter tracks how much of the split's logical
+// content has actually been handed out.
+type splitRecordReader struct {
+	inner    RecordReader
+	limit    int64 // split.End - split.Start; unbounded when the split's End is the "to EOF" sentinel
+	consumed int64
+	aligned  bool
+	done     bool
+}
+
+// newSplitRecordReader opens split.File, seeks to split.Start, and
+// returns a RecordReader over just that split's records. A split.File
+// that is gzip- or bzip2-compressed is transparently decompressed;
+// planSplits never gives a file with a compressed extension (see
+// splittable) a Start > 0, since a compressed stream can only be
+// decoded from its beginning.
+func newSplitRecordReader(split Split, format InputFormat) (*splitRecordReader, *os.File, error) {
+	f, err := os.Open(split.File)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open file:%s cause error:%w", split.File, err)
+	}
+	if split.Start > 0 {
+		if _, err := f.Seek(split.Start, io.SeekStart); err != nil {
+			f.Close()
+			return nil, nil, fmt.Errorf("seek file:%s cause error:%w", split.File, err)
+		}
+	}
+
+	r, err := decompress(bufio.NewReader(f))
+	if err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("decompress file:%s cause error:%w", split.File, err)
+	}
+
+	limit := int64(1<<63 - 1)
+	if split.End > 0 {
+		limit = split.End - split.Start
+	}
+
+	return &splitRecordReader{
+		inner:   format.NewRecordReader(r),
+		limit:   limit,
+		aligned: split.Start == 0,
+	}, f, nil
+}
+
+// readSplitContent reads every record in split and joins them back
+// into a single string (one record per line) for doMap's buffered
+// path, which hands mapF the whole input at once.
+func readSplitContent(split Split, format InputFormat) (string, error) {
+	reader, f, err := newSplitRecordReader(split, format)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var sb strings.Builder
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("read file:%s cause error:%w", split.File, err)
+		}
+		sb.WriteString(record)
+		sb.WriteByte('\n')
+	}
+	return sb.String(), nil
+}
+
+func (r *splitRecordReader) Read() (string, error) {
+	if r.done {
+		return "", io.EOF
+	}
+	if !r.aligned {
+		r.aligned = true
+		discarded, err := r.inner.Read()
+		if err != nil && err != io.EOF {
+			return "", err
+		}
+		// The discarded record's bytes are still real progress through
+		// the split's byte range even though they aren't yielded, so
+		// they must count toward limit too; otherwise every split after
+		// the first would under-count and read one record too many.
+		if err != io.EOF {
+			r.consumed += int64(len(discarded)) + 1
+		}
+		if r.consumed > r.limit {
+			r.done = true
+			return "", io.EOF
+		}
+	}
+	record, err := r.inner.Read()
+	if err != nil {
+		r.done = true
+		return "", err
+	}
+	r.consumed += int64(len(record)) + 1 // +1 for the delimiter Read() stripped
+	if r.consumed > r.limit {
+		r.done = true
+	}
+	return record, nil
+}
@@ -0,0 +1,97 @@
+package mapreduce
+
+import (
+	"context"
+	"io/ioutil"
+	"sort"
+	"testing"
+)
+
+func TestPlanSplitsZeroSplitSizeIsOneSplitPerFile(t *testing.T) {
+	// splitSize <= 0 must not touch the filesystem at all, so files
+	// that don't exist on disk (as in the RPC/coordinator tests) are
+	// still fine to plan.
+	splits, err := planSplits([]string{"does-not-exist-a", "does-not-exist-b"}, 0)
+	if err != nil {
+		t.Fatalf("planSplits: %v", err)
+	}
+	if len(splits) != 2 || splits[0] != (Split{File: "does-not-exist-a"}) || splits[1] != (Split{File: "does-not-exist-b"}) {
+		t.Fatalf("got %+v, want one unbounded split per file", splits)
+	}
+}
+
+func TestPlanSplitsDividesLargeFiles(t *testing.T) {
+	withTempDir(t, func(dir string) {
+		if err := ioutil.WriteFile("big", make([]byte, 25), 0666); err != nil {
+			t.Fatalf("write file: %v", err)
+		}
+		splits, err := planSplits([]string{"big"}, 10)
+		if err != nil {
+			t.Fatalf("planSplits: %v", err)
+		}
+		want := []Split{{File: "big", Start: 0, End: 10}, {File: "big", Start: 10, End: 20}, {File: "big", Start: 20, End: 25}}
+		if len(splits) != len(want) {
+			t.Fatalf("got %+v, want %+v", splits, want)
+		}
+		for i := range want {
+			if splits[i] != want[i] {
+				t.Fatalf("split %d: got %+v, want %+v", i, splits[i], want[i])
+			}
+		}
+	})
+}
+
+// TestDoMapSplitCoversEveryRecordExactlyOnce checks that reading a
+// file as several byte-range Splits (instead of one Split covering
+// the whole file) still gives every record to exactly one map task,
+// even when a Split boundary lands in the middle of a line.
+func TestDoMapSplitCoversEveryRecordExactlyOnce(t *testing.T) {
+	const jobName = "test"
+	const nReduce = 1
+	input := "the quick brown\nfox jumps over\nthe lazy dog\nthe fox runs\n"
+
+	withTempDir(t, func(dir string) {
+		if err := ioutil.WriteFile("in-0", []byte(input), 0666); err != nil {
+			t.Fatalf("write input: %v", err)
+		}
+		splits, err := planSplits([]string{"in-0"}, 20) // splits land mid-line
+		if err != nil {
+			t.Fatalf("planSplits: %v", err)
+		}
+		if len(splits) < 2 {
+			t.Fatalf("got %d splits, want at least 2 to actually exercise boundary alignment", len(splits))
+		}
+
+		for i, split := range splits {
+			opts := MapOptions{}
+			if err := doMap(context.Background(), jobName, i, split, nReduce, wordCountMapF, opts); err != nil {
+				t.Fatalf("doMap split %d: %v", i, err)
+			}
+		}
+
+		var got []string
+		for i := range splits {
+			for _, kv := range readPartition(t, JSONCodec{}, jobName, i, 0) {
+				got = append(got, kv.Key)
+			}
+		}
+		sort.Strings(got)
+
+		var want []string
+		for r := 0; r < 1; r++ {
+			for _, kv := range wordCountMapF("in-0", input) {
+				want = append(want, kv.Key)
+			}
+		}
+		sort.Strings(want)
+
+		if len(got) != len(want) {
+			t.Fatalf("got %d words across all splits, want %d (a record was dropped or duplicated): got=%v want=%v", len(got), len(want), got, want)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("got %v, want %v", got, want)
+			}
+		}
+	})
+}
@@ -0,0 +1,95 @@
+package mapreduce
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+// SampleKeys runs mapF over every file in files and returns up to
+// sampleSize of the keys it emits, strided evenly across the full
+// sorted set of keys rather than just the first sampleSize seen, so
+// the sample reflects the overall key distribution. sampleSize <= 0
+// returns every emitted key, sorted. Its result feeds
+// ComputePartitionBoundaries. SampleKeys is a plain pre-processing
+// step the caller runs before the job's real map phase, the same way
+// a SaltKey predicate is something the caller supplies rather than
+// something the framework derives on its own.
+func SampleKeys(mapF func(filename string, contents string) []KeyValue, files []string, sampleSize int) ([]string, error) {
+	var keys []string
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, fmt.Errorf("open file:%s cause error:%w", file, err)
+		}
+		content, err := readWhole(f, file)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, kv := range mapF(file, string(content)) {
+			keys = append(keys, kv.Key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	if sampleSize <= 0 || sampleSize >= len(keys) {
+		return keys, nil
+	}
+
+	sample := make([]string, sampleSize)
+	stride := float64(len(keys)) / float64(sampleSize)
+	for i := range sample {
+		sample[i] = keys[int(float64(i)*stride)]
+	}
+	return sample, nil
+}
+
+// ComputePartitionBoundaries picks nReduce-1 keys out of sample that
+// split it into nReduce roughly equal-sized ranges, for use with
+// NewTotalOrderPartitionF. sample need not already be sorted;
+// ComputePartitionBoundaries sorts a copy of it, so callers can pass
+// SampleKeys's result straight through. It returns nil when there is
+// nothing to split on (nReduce <= 1, or an empty sample), which
+// NewTotalOrderPartitionF treats as "everything goes to partition 0."
+func ComputePartitionBoundaries(sample []string, nReduce int) []string {
+	if nReduce <= 1 || len(sample) == 0 {
+		return nil
+	}
+
+	sorted := append([]string(nil), sample...)
+	sort.Strings(sorted)
+
+	boundaries := make([]string, 0, nReduce-1)
+	stride := float64(len(sorted)) / float64(nReduce)
+	for i := 1; i < nReduce; i++ {
+		idx := int(float64(i) * stride)
+		if idx >= len(sorted) {
+			idx = len(sorted) - 1
+		}
+		boundaries = append(boundaries, sorted[idx])
+	}
+	return boundaries
+}
+
+// NewTotalOrderPartitionF returns a MapOptions.PartitionF that routes
+// a key to a reduce task by range instead of by ihash: keys less than
+// or equal to boundaries[0] go to partition 0, keys between
+// boundaries[0] and boundaries[1] go to partition 1, and so on, with
+// everything past the last boundary landing in partition
+// len(boundaries). Since doReduce already writes each reduce task's
+// output sorted by key, pairing this with boundaries from
+// ComputePartitionBoundaries makes the job's output globally sorted:
+// mr-<job>-res-0 holds the smallest keys, the last result file holds
+// the largest, and every result file is internally sorted.
+//
+// The returned func ignores its nReduce argument; boundaries alone
+// fixes the number of partitions a key can land in, at
+// len(boundaries)+1, so callers must run the job with an nReduce that
+// agrees with it.
+func NewTotalOrderPartitionF(boundaries []string) func(key string, nReduce int) int {
+	return func(key string, nReduce int) int {
+		return sort.SearchStrings(boundaries, key)
+	}
+}
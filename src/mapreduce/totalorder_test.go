@@ -0,0 +1,133 @@
+package mapreduce
+
+import (
+	"context"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func wordsMapF(filename, contents string) []KeyValue {
+	var kvs []KeyValue
+	for _, w := range strings.Fields(contents) {
+		kvs = append(kvs, KeyValue{Key: w, Value: "1"})
+	}
+	return kvs
+}
+
+func TestSampleKeysStridesAcrossFullSortedSet(t *testing.T) {
+	withTempDir(t, func(dir string) {
+		if err := ioutil.WriteFile("in-0", []byte("d b a c"), 0666); err != nil {
+			t.Fatalf("write input: %v", err)
+		}
+
+		sample, err := SampleKeys(wordsMapF, []string{"in-0"}, 2)
+		if err != nil {
+			t.Fatalf("SampleKeys: %v", err)
+		}
+		if len(sample) != 2 {
+			t.Fatalf("SampleKeys returned %d keys, want 2", len(sample))
+		}
+		if !sort.StringsAreSorted(sample) {
+			t.Fatalf("SampleKeys result %v is not sorted", sample)
+		}
+	})
+}
+
+func TestSampleKeysSampleSizeAtLeastKeyCountReturnsAll(t *testing.T) {
+	withTempDir(t, func(dir string) {
+		if err := ioutil.WriteFile("in-0", []byte("b a"), 0666); err != nil {
+			t.Fatalf("write input: %v", err)
+		}
+
+		sample, err := SampleKeys(wordsMapF, []string{"in-0"}, 10)
+		if err != nil {
+			t.Fatalf("SampleKeys: %v", err)
+		}
+		want := []string{"a", "b"}
+		if !reflect.DeepEqual(sample, want) {
+			t.Fatalf("SampleKeys = %v, want %v", sample, want)
+		}
+	})
+}
+
+func TestComputePartitionBoundariesCount(t *testing.T) {
+	sample := []string{"e", "a", "d", "b", "c"}
+	boundaries := ComputePartitionBoundaries(sample, 3)
+	if len(boundaries) != 2 {
+		t.Fatalf("got %d boundaries, want 2 (nReduce - 1)", len(boundaries))
+	}
+	if !sort.StringsAreSorted(boundaries) {
+		t.Fatalf("boundaries %v are not sorted", boundaries)
+	}
+}
+
+func TestComputePartitionBoundariesDegenerateCases(t *testing.T) {
+	if got := ComputePartitionBoundaries([]string{"a"}, 1); got != nil {
+		t.Fatalf("nReduce == 1: got %v, want nil", got)
+	}
+	if got := ComputePartitionBoundaries(nil, 4); got != nil {
+		t.Fatalf("empty sample: got %v, want nil", got)
+	}
+}
+
+func TestNewTotalOrderPartitionFRoutesByRange(t *testing.T) {
+	partitionF := NewTotalOrderPartitionF([]string{"m"})
+
+	if p := partitionF("a", 2); p != 0 {
+		t.Fatalf("partitionF(\"a\") = %d, want 0", p)
+	}
+	if p := partitionF("m", 2); p != 0 {
+		t.Fatalf("partitionF(\"m\") = %d, want 0 (boundary is inclusive)", p)
+	}
+	if p := partitionF("z", 2); p != 1 {
+		t.Fatalf("partitionF(\"z\") = %d, want 1", p)
+	}
+}
+
+// TestSequentialTotalOrderPartitionProducesGloballySortedOutput runs a
+// full Sequential job with a range PartitionF built from
+// ComputePartitionBoundaries and checks that the job's result files
+// are not just each internally sorted (doReduce already guarantees
+// that) but sorted as a sequence: every key in mr-test-res-0 sorts
+// before every key in mr-test-res-1, and so on.
+func TestSequentialTotalOrderPartitionProducesGloballySortedOutput(t *testing.T) {
+	const jobName = "test"
+	const nReduce = 3
+
+	withTempDir(t, func(dir string) {
+		if err := ioutil.WriteFile("in-0", []byte("mango kiwi apple banana date fig grape lemon"), 0666); err != nil {
+			t.Fatalf("write input: %v", err)
+		}
+
+		sample, err := SampleKeys(wordsMapF, []string{"in-0"}, 0)
+		if err != nil {
+			t.Fatalf("SampleKeys: %v", err)
+		}
+		boundaries := ComputePartitionBoundaries(sample, nReduce)
+
+		firstReduceF := func(key string, values []string) string { return values[0] }
+		opts := MapOptions{PartitionF: NewTotalOrderPartitionF(boundaries)}
+		if err := Sequential(context.Background(), jobName, []string{"in-0"}, nReduce, wordsMapF, firstReduceF, opts); err != nil {
+			t.Fatalf("Sequential: %v", err)
+		}
+
+		var lastOfPrevFile string
+		for r := 0; r < nReduce; r++ {
+			kvs := decodeMergedFile(t, mergeName(jobName, r))
+			for i := 1; i < len(kvs); i++ {
+				if kvs[i-1].Key > kvs[i].Key {
+					t.Fatalf("result file %d not sorted: %q before %q", r, kvs[i-1].Key, kvs[i].Key)
+				}
+			}
+			if len(kvs) > 0 {
+				if lastOfPrevFile != "" && lastOfPrevFile > kvs[0].Key {
+					t.Fatalf("result file %d starts with %q, which sorts before the previous file's last key %q", r, kvs[0].Key, lastOfPrevFile)
+				}
+				lastOfPrevFile = kvs[len(kvs)-1].Key
+			}
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package mapreduce
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// TypedKeyValue is the generic counterpart to KeyValue: MapFunc and
+// ReduceFunc let a job's mapF and reduceF work with a typed key and
+// value instead of a pair of strings, while the framework still
+// marshals them to the same string-based KeyValue format doMap,
+// doReduce, and every Codec already understand. Sequential and
+// Coordinator take no typed parameters themselves; MapFunc and
+// ReduceFunc just adapt a typed function into the string-based
+// signature they already accept, so the two APIs interoperate freely.
+type TypedKeyValue[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+// MapFunc adapts a typed map function into the string-based mapF
+// signature doMap and Sequential expect, marshalling each typed key
+// and value to a string with encoding/json. It panics if a particular
+// key or value fails to marshal: that means K or V isn't
+// JSON-marshalable, a mistake in the job's own types rather than a
+// runtime failure a caller could retry or skip past the way it can a
+// failed disk write.
+func MapFunc[K comparable, V any](f func(filename string, contents string) []TypedKeyValue[K, V]) func(string, string) []KeyValue {
+	return func(filename, contents string) []KeyValue {
+		typed := f(filename, contents)
+		kvs := make([]KeyValue, len(typed))
+		for i, tkv := range typed {
+			kvs[i] = KeyValue{Key: marshalTyped(tkv.Key), Value: marshalTyped(tkv.Value)}
+		}
+		return kvs
+	}
+}
+
+// ReduceFunc adapts a typed reduce function into the string-based
+// reduceF signature doReduce expects: it unmarshals the key and every
+// intermediate value from JSON before calling f, then marshals f's
+// typed result back to a string for the final output file. It panics
+// for the same reason MapFunc's adapter does.
+func ReduceFunc[K comparable, V any](f func(key K, values []V) V) func(string, []string) string {
+	return func(key string, values []string) string {
+		typedValues := make([]V, len(values))
+		for i, v := range values {
+			typedValues[i] = unmarshalTyped[V](v)
+		}
+		return marshalTyped(f(unmarshalTyped[K](key), typedValues))
+	}
+}
+
+func marshalTyped[T any](v T) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("mapreduce: marshal %T: %v", v, err))
+	}
+	return string(b)
+}
+
+func unmarshalTyped[T any](s string) T {
+	var v T
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		panic(fmt.Sprintf("mapreduce: unmarshal %T: %v", v, err))
+	}
+	return v
+}
@@ -0,0 +1,110 @@
+package mapreduce
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestTypedSequentialWordCount runs a full Sequential word-count job
+// built entirely from MapFunc/ReduceFunc, so mapF and reduceF work
+// with a string key and an int value instead of a pair of strings,
+// checking that the typed adapters round-trip correctly through the
+// same intermediate file format the string-based API uses.
+func TestTypedSequentialWordCount(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mr-typed-test")
+	if err != nil {
+		t.Fatalf("create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+	defer os.Chdir(wd)
+
+	inFile := filepath.Join(dir, "in-0")
+	if err := ioutil.WriteFile(inFile, []byte("a a b"), 0666); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+
+	typedMapF := func(filename string, contents string) []TypedKeyValue[string, int] {
+		var kvs []TypedKeyValue[string, int]
+		for _, word := range strings.Fields(contents) {
+			kvs = append(kvs, TypedKeyValue[string, int]{Key: word, Value: 1})
+		}
+		return kvs
+	}
+	typedReduceF := func(key string, values []int) int {
+		sum := 0
+		for _, v := range values {
+			sum += v
+		}
+		return sum
+	}
+
+	const jobName = "test"
+	const nReduce = 2
+
+	err = Sequential(context.Background(), jobName, []string{inFile}, nReduce, MapFunc(typedMapF), ReduceFunc(typedReduceF), MapOptions{})
+	if err != nil {
+		t.Fatalf("Sequential: %v", err)
+	}
+
+	counts := make(map[string]int)
+	for r := 0; r < nReduce; r++ {
+		outFd, err := os.Open(mergeName(jobName, r))
+		if err != nil {
+			t.Fatalf("open reduce output %d: %v", r, err)
+		}
+		dec := json.NewDecoder(outFd)
+		for {
+			var kv KeyValue
+			if err := dec.Decode(&kv); err != nil {
+				break
+			}
+			var count int
+			if err := json.Unmarshal([]byte(kv.Value), &count); err != nil {
+				t.Fatalf("unmarshal count: %v", err)
+			}
+			var key string
+			if err := json.Unmarshal([]byte(kv.Key), &key); err != nil {
+				t.Fatalf("unmarshal key: %v", err)
+			}
+			counts[key] = count
+		}
+		outFd.Close()
+	}
+
+	if counts["a"] != 2 || counts["b"] != 1 {
+		t.Fatalf("got counts %v, want a=2 b=1", counts)
+	}
+}
+
+// TestMapFuncPanicsOnUnmarshalableValue checks that MapFunc's adapter
+// panics rather than silently dropping or mis-encoding a value that
+// cannot round-trip through JSON, since mapF has no error return for
+// the adapter to report the failure through.
+func TestMapFuncPanicsOnUnmarshalableValue(t *testing.T) {
+	type unmarshalable struct {
+		C chan int // channels are never JSON-marshalable
+	}
+	f := MapFunc(func(filename, contents string) []TypedKeyValue[string, unmarshalable] {
+		return []TypedKeyValue[string, unmarshalable]{{Key: "k", Value: unmarshalable{C: make(chan int)}}}
+	})
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("f did not panic on an unmarshalable value")
+		}
+	}()
+	f("in-0", "contents")
+}
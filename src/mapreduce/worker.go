@@ -0,0 +1,78 @@
+package mapreduce
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// RunWorker repeatedly asks the coordinator for work over RPC and
+// executes it, using mapF and reduceF as the user's map and reduce
+// functions, until the coordinator reports that the job is done or
+// ctx is done. partitionF and combineF are the same optional map-phase
+// hooks doMap takes; pass nil for both to get the default behavior.
+// ctx is passed down into doMap/doReduce so a cancellation or deadline
+// actually stops in-flight work instead of only being noticed between
+// tasks. Before each task, RunWorker resets the process's Counters so
+// that whatever mapF or reduceF reports via Count while running it is
+// exactly that task's counts, and reports them to the coordinator
+// alongside the task's success or failure.
+func RunWorker(
+	ctx context.Context,
+	mapF func(filename string, contents string) []KeyValue,
+	reduceF func(key string, values []string) string,
+	partitionF func(key string, nReduce int) int,
+	combineF func(key string, values []string) string,
+) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		args := GetTaskArgs{}
+		reply := GetTaskReply{}
+		if ok := call("Coordinator.GetTask", &args, &reply); !ok {
+			return
+		}
+
+		switch reply.Type {
+		case MapTask:
+			opts := MapOptions{
+				Codec:         codecByName(reply.Opts.Codec),
+				PartitionF:    partitionF,
+				CombineF:      combineF,
+				Streaming:     reply.Opts.Streaming,
+				ChunkSize:     reply.Opts.ChunkSize,
+				Compression:   reply.Opts.Compression,
+				SecondarySort: reply.Opts.SecondarySort,
+			}
+			split := Split{File: reply.File, Start: reply.SplitStart, End: reply.SplitEnd}
+			taskCounters = NewCounters()
+			err := doMap(ctx, reply.JobName, reply.TaskNum, split, reply.NReduce, mapF, opts)
+			if err != nil {
+				log.Printf("map task %d failed: %v", reply.TaskNum, err)
+			}
+			reportTask(MapTask, reply.TaskNum, err == nil, taskCounters.Snapshot())
+		case ReduceTask:
+			taskCounters = NewCounters()
+			err := doReduce(ctx, reply.JobName, reply.TaskNum, mergeName(reply.JobName, reply.TaskNum), reply.NMap, reduceF, codecByName(reply.Opts.Codec), reply.Opts.SecondarySort)
+			if err != nil {
+				log.Printf("reduce task %d failed: %v", reply.TaskNum, err)
+			}
+			reportTask(ReduceTask, reply.TaskNum, err == nil, taskCounters.Snapshot())
+		case WaitTask:
+			select {
+			case <-time.After(time.Second):
+			case <-ctx.Done():
+				return
+			}
+		case ExitTask:
+			return
+		}
+	}
+}
+
+func reportTask(t TaskType, taskNum int, success bool, counters map[string]int64) {
+	args := ReportTaskArgs{Type: t, TaskNum: taskNum, Success: success, Counters: counters}
+	call("Coordinator.ReportTask", &args, &ReportTaskReply{})
+}
@@ -0,0 +1,96 @@
+package mapreduce
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// TestDoMapWriteBufferSizeDoesNotAffectOutput checks that
+// WriteBufferSize only changes how often intermediate file writes
+// flush to disk, not what ends up in them, across every path that
+// writes intermediate files (buffered, streaming, and spill).
+func TestDoMapWriteBufferSizeDoesNotAffectOutput(t *testing.T) {
+	const jobName = "test"
+	const nReduce = 3
+	input := "the quick brown\nfox jumps over\nthe lazy dog\nthe fox runs"
+
+	read := func(opts MapOptions) map[int][]KeyValue {
+		out := map[int][]KeyValue{}
+		withTempDir(t, func(dir string) {
+			if err := ioutil.WriteFile("in-0", []byte(input), 0666); err != nil {
+				t.Fatalf("write input: %v", err)
+			}
+			if err := doMap(context.Background(), jobName, 0, Split{File: "in-0"}, nReduce, wordCountMapF, opts); err != nil {
+				t.Fatalf("doMap: %v", err)
+			}
+			for r := 0; r < nReduce; r++ {
+				out[r] = readPartition(t, JSONCodec{}, jobName, 0, r)
+			}
+		})
+		return out
+	}
+
+	cases := map[string]MapOptions{
+		"buffered, default buffer": {},
+		"buffered, tiny buffer":    {WriteBufferSize: 1},
+		"streaming, tiny buffer":   {Streaming: true, ChunkSize: 16, WriteBufferSize: 1},
+		"spill, tiny buffer":       {SpillSize: 8, WriteBufferSize: 1},
+	}
+
+	want := read(MapOptions{})
+	for name, opts := range cases {
+		got := read(opts)
+		for r := 0; r < nReduce; r++ {
+			a, b := want[r], got[r]
+			sort.Slice(a, func(i, j int) bool { return a[i].Key < a[j].Key })
+			sort.Slice(b, func(i, j int) bool { return b[i].Key < b[j].Key })
+			if !reflect.DeepEqual(a, b) {
+				t.Errorf("%s: partition %d: got %v, want %v", name, r, b, a)
+			}
+		}
+	}
+}
+
+// BenchmarkDoMapManySmallKVs measures the effect of WriteBufferSize on
+// a many-small-KV workload, where each Encode call is small enough
+// that an unbuffered *os.File turns into one write(2) syscall per KV.
+func BenchmarkDoMapManySmallKVs(b *testing.B) {
+	const jobName = "bench"
+	const nReduce = 4
+
+	var sb []byte
+	for i := 0; i < 20000; i++ {
+		sb = append(sb, []byte(fmt.Sprintf("key%d ", i))...)
+	}
+	input := string(sb)
+
+	for _, bufSize := range []int{1, DefaultWriteBufferSize} {
+		bufSize := bufSize
+		b.Run(fmt.Sprintf("bufSize=%d", bufSize), func(b *testing.B) {
+			dir, err := ioutil.TempDir("", "mr-write-buffer-bench")
+			if err != nil {
+				b.Fatalf("create temp dir: %v", err)
+			}
+			defer os.RemoveAll(dir)
+			if err := os.Chdir(dir); err != nil {
+				b.Fatalf("chdir: %v", err)
+			}
+			if err := ioutil.WriteFile("in-0", []byte(input), 0666); err != nil {
+				b.Fatalf("write input: %v", err)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				opts := MapOptions{WriteBufferSize: bufSize}
+				if err := doMap(context.Background(), jobName, i, Split{File: "in-0"}, nReduce, wordCountMapF, opts); err != nil {
+					b.Fatalf("doMap: %v", err)
+				}
+			}
+		})
+	}
+}